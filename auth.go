@@ -0,0 +1,149 @@
+package httpr
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxTokenRefreshWindow is how long before expiry a cached token is refreshed at most. tokens that live for less
+// than twice as long are refreshed halfway through their lifetime.
+const maxTokenRefreshWindow = 30 * time.Second
+
+// TokenSource provides bearer tokens for [BearerAuth].
+type TokenSource interface {
+	// Token fetches a new token along with when it expires. a zero expiry means the token doesn't expire.
+	Token(ctx context.Context) (token string, expiry time.Time, err error)
+}
+
+// TokenSourceFunc is a function that implements [TokenSource].
+type TokenSourceFunc func(ctx context.Context) (string, time.Time, error)
+
+func (f TokenSourceFunc) Token(ctx context.Context) (string, time.Time, error) {
+	return f(ctx)
+}
+
+// tokenInvalidator is implemented by token sources that cache tokens, so that tokens rejected by the server aren't
+// handed out again.
+type tokenInvalidator interface {
+	invalidate(token string)
+}
+
+// bearerAuth sets the Authorization header of every request to a token obtained from a [TokenSource].
+type bearerAuth struct {
+	source TokenSource
+
+	// refreshing serializes calls to the token source
+	refreshing chan struct{}
+
+	mu        sync.Mutex
+	token     string
+	refreshAt time.Time
+}
+
+var _ Interceptor = (*bearerAuth)(nil)
+
+// BearerAuth authenticates requests with a bearer token obtained from source. the token is cached and refreshed
+// shortly before it expires. concurrent requests share a single refresh. if the server responds with 401
+// Unauthorized, the token is refreshed and the request is sent once more.
+func BearerAuth(source TokenSource) Option {
+	return interceptOption{&bearerAuth{
+		source:     source,
+		refreshing: make(chan struct{}, 1),
+	}}
+}
+
+func (b *bearerAuth) Handle(ctx context.Context, req *http.Request, next Interceptor) (*http.Response, error) {
+	token, err := b.current(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := next.Handle(ctx, req, nil)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	// the 401 is returned as is if the request can't be sent again
+	replay, err := rewindRequest(req)
+	if err != nil {
+		return resp, nil //nolint:nilerr // the caller gets the server's response
+	}
+
+	if source, ok := b.source.(tokenInvalidator); ok {
+		source.invalidate(token)
+	}
+
+	token, err = b.current(ctx, token)
+	discardResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	replay.Header.Set("Authorization", "Bearer "+token)
+
+	return next.Handle(ctx, replay, nil)
+}
+
+// current returns a valid token, fetching a new one if there is none, it's about to expire, or it's the rejected
+// token.
+func (b *bearerAuth) current(ctx context.Context, rejected string) (string, error) {
+	if token, ok := b.cached(rejected, time.Now()); ok {
+		return token, nil
+	}
+
+	select {
+	case b.refreshing <- struct{}{}:
+		defer func() { <-b.refreshing }()
+	case <-ctx.Done():
+		return "", fmt.Errorf("interrupted while waiting for token refresh: %w", context.Cause(ctx))
+	}
+
+	// another request may have refreshed the token while this one was waiting
+	if token, ok := b.cached(rejected, time.Now()); ok {
+		return token, nil
+	}
+
+	token, expiry, err := b.source.Token(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get token: %w", err)
+	}
+
+	now := time.Now()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.token = token
+	b.refreshAt = tokenRefreshAt(expiry, now)
+
+	return token, nil
+}
+
+func (b *bearerAuth) cached(rejected string, now time.Time) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.token == "" || b.token == rejected {
+		return "", false
+	}
+
+	if !b.refreshAt.IsZero() && !now.Before(b.refreshAt) {
+		return "", false
+	}
+
+	return b.token, true
+}
+
+// tokenRefreshAt returns when a token fetched at now should be refreshed. a zero time means never.
+func tokenRefreshAt(expiry time.Time, now time.Time) time.Time {
+	if expiry.IsZero() {
+		return time.Time{}
+	}
+
+	return expiry.Add(-min(maxTokenRefreshWindow, expiry.Sub(now)/2))
+}
@@ -0,0 +1,58 @@
+package httpr
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// BasicAuth authenticates requests with HTTP Basic authentication (RFC 7617).
+func BasicAuth(username, password string) Option {
+	credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return Header("Authorization", "Basic "+credentials)
+}
+
+// APIKeyLocation is where an API key is sent.
+type APIKeyLocation int
+
+const (
+	// APIKeyInHeader sends the API key in a request header.
+	APIKeyInHeader APIKeyLocation = iota
+	// APIKeyInQuery sends the API key as a query parameter.
+	APIKeyInQuery
+)
+
+// APIKey authenticates requests with an API key sent in the header or query parameter called name.
+func APIKey(location APIKeyLocation, name, value string) Option {
+	if location == APIKeyInHeader {
+		return Header(name, value)
+	}
+
+	return Intercept(HandleFunc(func(ctx context.Context, req *http.Request, next Interceptor) (*http.Response, error) {
+		req.URL.RawQuery = setQueryParam(req.URL.RawQuery, name, value)
+		return next.Handle(ctx, req, nil)
+	}))
+}
+
+// setQueryParam sets the query parameter called name to value, replacing it if it's already set (e.g. when the
+// request is sent again). the rest of the query is kept as is rather than decoded and encoded again, which could
+// change it (e.g. the order of parameters).
+func setQueryParam(query, name, value string) string {
+	param := url.QueryEscape(name) + "=" + url.QueryEscape(value)
+	if query == "" {
+		return param
+	}
+
+	params := strings.Split(query, "&")
+	params = slices.DeleteFunc(params, func(p string) bool {
+		key, _, _ := strings.Cut(p, "=")
+		unescaped, err := url.QueryUnescape(key)
+
+		return err == nil && unescaped == name
+	})
+
+	return strings.Join(append(params, param), "&")
+}
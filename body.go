@@ -0,0 +1,25 @@
+package httpr
+
+import (
+	"io"
+	"sync"
+)
+
+// closeHook calls a function once the body it wraps is closed, e.g. to keep a context alive or a slot taken for as
+// long as the body is being read.
+type closeHook struct {
+	io.ReadCloser
+	once sync.Once
+	hook func()
+}
+
+func onClose(body io.ReadCloser, hook func()) io.ReadCloser {
+	return &closeHook{ReadCloser: body, hook: hook}
+}
+
+func (c *closeHook) Close() error {
+	err := c.ReadCloser.Close()
+	c.once.Do(c.hook)
+
+	return err
+}
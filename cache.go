@@ -0,0 +1,606 @@
+package httpr
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// CacheStatusHeader is set on every response that passes through a [Cache] to tell whether it was served from
+// the cache.
+const CacheStatusHeader = "X-Httpr-Cache"
+
+const (
+	// CacheHit means the response was served from the cache without contacting the server.
+	CacheHit = "HIT"
+	// CacheMiss means no usable response was stored and the response came from the server.
+	CacheMiss = "MISS"
+	// CacheRevalidated means a stored response had to be validated with the server before it could be used.
+	CacheRevalidated = "REVALIDATED"
+	// CacheStale means a stale response was served, either while it's refreshed in the background
+	// (stale-while-revalidate) or because the server couldn't be reached (stale-if-error).
+	CacheStale = "STALE"
+)
+
+// cacheableStatusCodes are the status codes that can be stored (RFC 9110 section 15.1).
+var cacheableStatusCodes = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// Cache is an interceptor that stores responses and serves them without contacting the server for as long as they
+// are fresh, following the caching rules of RFC 9111 (Cache-Control, Expires, Age and Vary). stale responses with an
+// ETag or Last-Modified header are revalidated with a conditional request, and may still be served as allowed by
+// stale-while-revalidate and stale-if-error (RFC 5861). only GET requests are served from the cache. requests with
+// unsafe methods (e.g. POST) invalidate the stored response for their URL and credentials.
+//
+// responses are stored per Authorization header, so they're only served to requests with the same credentials.
+// credentials added after the cache (e.g. by interceptors that come after it, or by signers) can't be told apart,
+// so responses to such requests aren't stored.
+type Cache struct {
+	shared bool
+	store  CacheStore
+
+	mu           sync.Mutex
+	revalidating map[string]bool
+}
+
+var _ Interceptor = (*Cache)(nil)
+
+// cacheEntry is a stored response along with what's needed to compute its age.
+type cacheEntry struct {
+	StatusCode   int               `json:"statusCode"`
+	Header       http.Header       `json:"header"`
+	Body         []byte            `json:"body"`
+	RequestTime  time.Time         `json:"requestTime"`
+	ResponseTime time.Time         `json:"responseTime"`
+	Vary         map[string]string `json:"vary"`
+}
+
+type CacheOption func(*Cache)
+
+// WithSharedCache makes the cache behave like a shared cache: responses marked private or sent in response to
+// requests with an Authorization header aren't stored, and s-maxage takes precedence over max-age. by default the
+// cache is a private cache, meant to be used by a single client.
+func WithSharedCache() CacheOption {
+	return func(c *Cache) {
+		c.shared = true
+	}
+}
+
+// WithCacheStore sets where the cache keeps its entries. Defaults to a [MemoryCacheStore] holding up to 32MB.
+func WithCacheStore(store CacheStore) CacheOption {
+	return func(c *Cache) {
+		c.store = store
+	}
+}
+
+// NewCache creates an HTTP cache. use it with [Intercept].
+func NewCache(opts ...CacheOption) *Cache {
+	c := &Cache{
+		store:        NewMemoryCacheStore(32 << 20),
+		revalidating: make(map[string]bool),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+func (c *Cache) Handle(ctx context.Context, req *http.Request, next Interceptor) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return c.passthrough(ctx, req, next)
+	}
+
+	reqCC := parseCacheControl(req.Header)
+	if reqCC.has("no-store") {
+		return next.Handle(ctx, req, nil)
+	}
+
+	key := cacheKey(req)
+	status := CacheMiss
+
+	entry := c.load(ctx, key)
+	if entry != nil && !entry.matches(req) {
+		entry = nil
+	}
+
+	// background refreshes always go to the server
+	if entry != nil && ctx.Value(revalidateKey{}) != c {
+		now := time.Now()
+		if c.fresh(entry, reqCC, now) {
+			return c.serve(ctx, req, entry, CacheHit), nil
+		}
+
+		if !reqCC.has("no-cache") && c.servableStale(entry, reqCC, "stale-while-revalidate", now) {
+			c.revalidate(ctx, key, req, next)
+			return c.serve(ctx, req, entry, CacheStale), nil
+		}
+
+		status = CacheRevalidated
+	}
+
+	// only validate the stored response if the caller didn't make the request conditional themselves, in which
+	// case they expect to see the 304
+	validate := entry != nil && entry.hasValidators() && !isConditional(req)
+
+	// interceptors after the cache get a copy since they may change the request (e.g. add credentials), which the
+	// cache has to notice before storing the response
+	upstreamReq := req.Clone(ctx)
+	if validate {
+		upstreamReq = entry.conditional(req)
+	}
+
+	requestTime := time.Now()
+	resp, err := next.Handle(ctx, upstreamReq, nil)
+	if entry != nil && ctx.Err() == nil && isOriginError(resp, err) &&
+		c.servableStale(entry, reqCC, "stale-if-error", time.Now()) {
+		if err == nil {
+			discardResponse(resp)
+		}
+
+		return c.serve(ctx, req, entry, CacheStale), nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if validate && resp.StatusCode == http.StatusNotModified {
+		return c.revalidated(ctx, key, req, entry, resp, requestTime), nil
+	}
+
+	if c.storable(req, resp) {
+		if err := c.save(ctx, key, req, resp, requestTime); err != nil {
+			return nil, err
+		}
+	}
+
+	markCacheStatus(ctx, resp, status)
+
+	return resp, nil
+}
+
+// revalidateKey marks background refreshes started by the [Cache] stored in the context.
+type revalidateKey struct{}
+
+// revalidate refreshes the stored response for key in the background. the refresh goes through the same interceptor
+// chain as the original request, but isn't cancelled along with it.
+func (c *Cache) revalidate(ctx context.Context, key string, req *http.Request, next Interceptor) {
+	c.mu.Lock()
+	if c.revalidating[key] {
+		c.mu.Unlock()
+		return
+	}
+	c.revalidating[key] = true
+	c.mu.Unlock()
+
+	chain, ok := ctx.Value(chainKey{}).(Interceptor)
+	if !ok {
+		chain = next
+	}
+
+	ctx = context.WithValue(context.WithoutCancel(ctx), revalidateKey{}, c)
+	req = req.Clone(ctx)
+
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.revalidating, key)
+			c.mu.Unlock()
+		}()
+
+		if resp, err := chain.Handle(ctx, req, nil); err == nil {
+			discardResponse(resp)
+		}
+	}()
+}
+
+// passthrough sends requests that can't be served from the cache, invalidating the stored response for the URL
+// if the request may have changed it (RFC 9111 section 4.4).
+func (c *Cache) passthrough(ctx context.Context, req *http.Request, next Interceptor) (*http.Response, error) {
+	resp, err := next.Handle(ctx, req, nil)
+	if err != nil || isSafeMethod(req.Method) {
+		return resp, err
+	}
+
+	if resp.StatusCode < http.StatusBadRequest {
+		_ = c.store.Delete(ctx, cacheKey(req))
+	}
+
+	return resp, nil
+}
+
+// revalidated updates the stored entry with the headers of a 304 response to a conditional request and serves it
+// (RFC 9111 section 4.3.4).
+func (c *Cache) revalidated(
+	ctx context.Context, key string, req *http.Request, entry *cacheEntry, resp *http.Response, requestTime time.Time,
+) *http.Response {
+	discardResponse(resp)
+
+	for name, values := range resp.Header {
+		switch name {
+		case "Content-Length", "Content-Encoding", "Transfer-Encoding", CacheStatusHeader:
+			continue
+		}
+
+		entry.Header[name] = values
+	}
+
+	entry.RequestTime = requestTime
+	entry.ResponseTime = time.Now()
+
+	if data, err := json.Marshal(entry); err == nil {
+		_ = c.store.Set(ctx, key, data)
+	}
+
+	return c.serve(ctx, req, entry, CacheRevalidated)
+}
+
+// load returns the entry stored under key, or nil if there is none or it can't be read.
+func (c *Cache) load(ctx context.Context, key string) *cacheEntry {
+	data, ok, err := c.store.Get(ctx, key)
+	if err != nil || !ok {
+		return nil
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil
+	}
+
+	return &entry
+}
+
+// save reads the response body so that it can be stored, and replaces it with a copy for the caller.
+func (c *Cache) save(ctx context.Context, key string, req *http.Request, resp *http.Response, requestTime time.Time) error {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read response body for caching: %w", err)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	entry := &cacheEntry{
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header.Clone(),
+		Body:         body,
+		RequestTime:  requestTime,
+		ResponseTime: time.Now(),
+		Vary:         map[string]string{},
+	}
+
+	for _, name := range varyHeaders(resp.Header) {
+		entry.Vary[name] = strings.Join(req.Header.Values(name), ",")
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+
+	// failing to store the response shouldn't fail the request
+	_ = c.store.Set(ctx, key, data)
+
+	return nil
+}
+
+// storable decides whether a response may be stored (RFC 9111 section 3).
+func (c *Cache) storable(req *http.Request, resp *http.Response) bool {
+	if !cacheableStatusCodes[resp.StatusCode] {
+		return false
+	}
+
+	respCC := parseCacheControl(resp.Header)
+	if respCC.has("no-store") {
+		return false
+	}
+
+	if c.shared && respCC.has("private") {
+		return false
+	}
+
+	if c.shared && req.Header.Get("Authorization") != "" &&
+		!respCC.has("public") && !respCC.has("s-maxage") && !respCC.has("must-revalidate") {
+		return false
+	}
+
+	if !sentAsKeyed(req, resp) {
+		return false
+	}
+
+	for _, name := range varyHeaders(resp.Header) {
+		if name == "*" {
+			return false
+		}
+	}
+
+	// responses that can't be served without validation are still worth storing if they can be validated
+	return c.lifetime(resp.Header) > 0 || hasValidators(resp.Header)
+}
+
+// fresh decides whether a stored response can be served without contacting the server (RFC 9111 section 4.2).
+func (c *Cache) fresh(entry *cacheEntry, reqCC cacheControl, now time.Time) bool {
+	if reqCC.has("no-cache") || parseCacheControl(entry.Header).has("no-cache") {
+		return false
+	}
+
+	age := entry.age(now)
+	if maxAge, ok := reqCC.seconds("max-age"); ok && age > maxAge {
+		return false
+	}
+
+	lifetime := c.lifetime(entry.Header)
+	if minFresh, ok := reqCC.seconds("min-fresh"); ok {
+		lifetime -= minFresh
+	}
+
+	return lifetime > age
+}
+
+// servableStale decides whether a stale response may still be served within the window given by directive,
+// i.e. stale-while-revalidate or stale-if-error (RFC 5861).
+func (c *Cache) servableStale(entry *cacheEntry, reqCC cacheControl, directive string, now time.Time) bool {
+	respCC := parseCacheControl(entry.Header)
+	if respCC.has("must-revalidate") || respCC.has("no-cache") || (c.shared && respCC.has("proxy-revalidate")) {
+		return false
+	}
+
+	window, ok := respCC.seconds(directive)
+	if requested, requestedOK := reqCC.seconds(directive); requestedOK && directive == "stale-if-error" {
+		window, ok = requested, true
+	}
+
+	if !ok {
+		return false
+	}
+
+	return entry.age(now)-c.lifetime(entry.Header) <= window
+}
+
+// lifetime returns how long a response stays fresh after it was generated (RFC 9111 section 4.2.1).
+func (c *Cache) lifetime(header http.Header) time.Duration {
+	cc := parseCacheControl(header)
+
+	if c.shared {
+		if sMaxAge, ok := cc.seconds("s-maxage"); ok {
+			return sMaxAge
+		}
+	}
+
+	if maxAge, ok := cc.seconds("max-age"); ok {
+		return maxAge
+	}
+
+	if expires := header.Get("Expires"); expires != "" {
+		expiresAt, err := http.ParseTime(expires)
+		if err != nil {
+			// invalid dates, e.g. "0", represent a time in the past
+			return 0
+		}
+
+		date, err := http.ParseTime(header.Get("Date"))
+		if err != nil {
+			return 0
+		}
+
+		return expiresAt.Sub(date)
+	}
+
+	return 0
+}
+
+// serve builds a response from a stored entry.
+func (c *Cache) serve(ctx context.Context, req *http.Request, entry *cacheEntry, status string) *http.Response {
+	header := entry.Header.Clone()
+	header.Set("Age", strconv.FormatInt(int64(entry.age(time.Now())/time.Second), 10))
+
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.StatusCode, http.StatusText(entry.StatusCode)),
+		StatusCode:    entry.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       req,
+	}
+
+	markCacheStatus(ctx, resp, status)
+
+	return resp
+}
+
+// age returns how long ago the stored response was generated by the server (RFC 9111 section 4.2.3).
+func (e *cacheEntry) age(now time.Time) time.Duration {
+	var apparentAge time.Duration
+	if date, err := http.ParseTime(e.Header.Get("Date")); err == nil {
+		apparentAge = max(e.ResponseTime.Sub(date), 0)
+	}
+
+	var ageValue time.Duration
+	if seconds, err := strconv.ParseInt(e.Header.Get("Age"), 10, 64); err == nil {
+		ageValue = time.Duration(seconds) * time.Second
+	}
+
+	responseDelay := e.ResponseTime.Sub(e.RequestTime)
+	correctedInitialAge := max(apparentAge, ageValue+responseDelay)
+	residentTime := now.Sub(e.ResponseTime)
+
+	return correctedInitialAge + residentTime
+}
+
+// matches reports whether the stored response can be used for req given the headers it varies on.
+func (e *cacheEntry) matches(req *http.Request) bool {
+	for name, value := range e.Vary {
+		if strings.Join(req.Header.Values(name), ",") != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (e *cacheEntry) hasValidators() bool {
+	return hasValidators(e.Header)
+}
+
+// conditional returns a copy of req that asks the server to only send the response if it differs from the stored
+// one (RFC 9110 section 13.1).
+func (e *cacheEntry) conditional(req *http.Request) *http.Request {
+	req = req.Clone(req.Context())
+
+	if etag := e.Header.Get("Etag"); etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	if lastModified := e.Header.Get("Last-Modified"); lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	return req
+}
+
+// isOriginError reports whether the server couldn't be reached or failed to handle the request (RFC 5861 section 4).
+func isOriginError(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+func hasValidators(header http.Header) bool {
+	return header.Get("Etag") != "" || header.Get("Last-Modified") != ""
+}
+
+func isConditional(req *http.Request) bool {
+	for _, name := range []string{"If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since", "If-Range"} {
+		if req.Header.Get(name) != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func markCacheStatus(ctx context.Context, resp *http.Response, status string) {
+	resp.Header.Set(CacheStatusHeader, status)
+	observationFromContext(ctx).annotate(attribute.String("cache.status", strings.ToLower(status)))
+}
+
+// cacheKey keys responses on the URL and, for authenticated requests, a hash of the credentials so that they're
+// never served to someone else.
+func cacheKey(req *http.Request) string {
+	key := http.MethodGet + " " + req.URL.String()
+
+	if authorization := req.Header.Get("Authorization"); authorization != "" {
+		sum := sha256.Sum256([]byte(authorization))
+		key += " " + hex.EncodeToString(sum[:])
+	}
+
+	return key
+}
+
+// sentAsKeyed reports whether the request that resp answers was sent with the URL and credentials the cache keyed
+// req on, i.e. no interceptor after the cache added credentials (e.g. an API key in the query or a signature).
+func sentAsKeyed(req *http.Request, resp *http.Response) bool {
+	sent := resp.Request
+	if sent == nil {
+		return true
+	}
+
+	// the first request of a redirect chain is the one the cache handed over
+	for sent.Response != nil && sent.Response.Request != nil {
+		sent = sent.Response.Request
+	}
+
+	return sent.URL.String() == req.URL.String() && sent.Header.Get("Authorization") == req.Header.Get("Authorization")
+}
+
+func varyHeaders(header http.Header) []string {
+	var names []string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+
+	return names
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// cacheControl holds the directives of a Cache-Control header, keyed by their lowercased name.
+type cacheControl map[string]string
+
+func parseCacheControl(header http.Header) cacheControl {
+	cc := cacheControl{}
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name != "" {
+				cc[strings.ToLower(name)] = strings.Trim(arg, `"`)
+			}
+		}
+	}
+
+	return cc
+}
+
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
+
+func (cc cacheControl) seconds(directive string) (time.Duration, bool) {
+	value, ok := cc[directive]
+	if !ok {
+		return 0, false
+	}
+
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+
+	return time.Duration(seconds) * time.Second, true
+}
@@ -0,0 +1,178 @@
+package httpr
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CacheStore is where a [Cache] keeps its entries. entries are opaque, serialized responses. implementations must be
+// safe for concurrent use. errors returned by a store never fail a request; the cache treats them as misses.
+type CacheStore interface {
+	// Get returns the entry stored under key. the second return value is false if there is none.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores an entry under key, replacing any existing one.
+	Set(ctx context.Context, key string, entry []byte) error
+	// Delete removes the entry stored under key, if any.
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryCacheStore is an in-memory [CacheStore] that evicts the least recently used entries once the total size
+// of the stored entries exceeds a limit.
+type MemoryCacheStore struct {
+	maxBytes int
+
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+var _ CacheStore = (*MemoryCacheStore)(nil)
+
+type memoryCacheItem struct {
+	key   string
+	entry []byte
+}
+
+// NewMemoryCacheStore creates an in-memory store that holds at most maxBytes worth of keys and entries.
+func NewMemoryCacheStore(maxBytes int) *MemoryCacheStore {
+	return &MemoryCacheStore{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+func (m *MemoryCacheStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	m.order.MoveToFront(element)
+
+	item, _ := element.Value.(*memoryCacheItem)
+	return item.entry, true, nil
+}
+
+func (m *MemoryCacheStore) Set(_ context.Context, key string, entry []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.remove(key)
+
+	size := len(key) + len(entry)
+	if size > m.maxBytes {
+		return nil
+	}
+
+	m.entries[key] = m.order.PushFront(&memoryCacheItem{key: key, entry: entry})
+	m.size += size
+
+	for m.size > m.maxBytes {
+		oldest, _ := m.order.Back().Value.(*memoryCacheItem)
+		m.remove(oldest.key)
+	}
+
+	return nil
+}
+
+func (m *MemoryCacheStore) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.remove(key)
+
+	return nil
+}
+
+func (m *MemoryCacheStore) remove(key string) {
+	element, ok := m.entries[key]
+	if !ok {
+		return
+	}
+
+	item, _ := m.order.Remove(element).(*memoryCacheItem)
+	delete(m.entries, key)
+	m.size -= len(item.key) + len(item.entry)
+}
+
+// DiskCacheStore is a [CacheStore] that keeps every entry in its own file within a directory, so that the cache
+// survives restarts.
+type DiskCacheStore struct {
+	dir string
+}
+
+var _ CacheStore = (*DiskCacheStore)(nil)
+
+// NewDiskCacheStore creates a store that keeps its entries in dir, creating the directory if needed.
+func NewDiskCacheStore(dir string) (*DiskCacheStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	return &DiskCacheStore{dir: dir}, nil
+}
+
+func (d *DiskCacheStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	entry, err := os.ReadFile(d.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+
+	return entry, true, nil
+}
+
+func (d *DiskCacheStore) Set(_ context.Context, key string, entry []byte) error {
+	// write to a temporary file first so that readers never see a partially written entry
+	tmp, err := os.CreateTemp(d.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache entry: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(entry); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), d.path(key)); err != nil {
+		return fmt.Errorf("failed to store cache entry: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DiskCacheStore) Delete(_ context.Context, key string) error {
+	err := os.Remove(d.path(key))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete cache entry: %w", err)
+	}
+
+	return nil
+}
+
+// path hashes the key so that it can safely be used as a file name.
+func (d *DiskCacheStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:]))
+}
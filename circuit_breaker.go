@@ -0,0 +1,309 @@
+package httpr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrCircuitOpen is returned without sending the request when the circuit for the request's host is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of the circuit for a single host.
+type CircuitState int
+
+const (
+	// CircuitClosed lets requests through and tracks their failures.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails requests fast with [ErrCircuitOpen] until the cool-down has passed.
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of probe requests through to decide whether to close or re-open.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+// CircuitBreaker is an interceptor that stops sending requests to a host after it has failed too often and
+// lets a few probe requests through once a cool-down has passed. circuits are tracked independently per host.
+type CircuitBreaker struct {
+	failureRatio        float64
+	minRequests         int64
+	consecutiveFailures int
+	window              time.Duration
+	coolDown            time.Duration
+	halfOpenRequests    int
+	isFailure           func(resp *http.Response, err error) bool
+	onStateChange       func(host string, from, to CircuitState)
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+var _ Interceptor = (*CircuitBreaker)(nil)
+
+type circuit struct {
+	state               CircuitState
+	openedAt            time.Time
+	consecutiveFailures int
+	// probes is the number of probe requests in flight, and round counts the times the circuit became half-open so
+	// that probes from an earlier round aren't mistaken for current ones
+	probes   int
+	round    int
+	requests slidingCounter
+	failures slidingCounter
+}
+
+type CircuitBreakerOption func(*CircuitBreaker)
+
+// WithFailureRatio opens the circuit once at least minRequests were sent within the failure window and the ratio
+// of those that failed reaches ratio. Defaults to 0.5 and 10.
+func WithFailureRatio(ratio float64, minRequests int) CircuitBreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.failureRatio = ratio
+		cb.minRequests = int64(minRequests)
+	}
+}
+
+// WithConsecutiveFailures opens the circuit after n failures in a row. Defaults to 5.
+func WithConsecutiveFailures(n int) CircuitBreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.consecutiveFailures = n
+	}
+}
+
+// WithFailureWindow sets how far back the failure ratio looks. Defaults to 10s.
+func WithFailureWindow(d time.Duration) CircuitBreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.window = d
+	}
+}
+
+// WithCoolDown sets how long the circuit stays open before letting probe requests through. Defaults to 30s.
+func WithCoolDown(d time.Duration) CircuitBreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.coolDown = d
+	}
+}
+
+// WithHalfOpenRequests sets how many probe requests may be in flight while the circuit is half-open. Defaults to 1.
+func WithHalfOpenRequests(n int) CircuitBreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.halfOpenRequests = n
+	}
+}
+
+// WithFailureCondition decides which outcomes count as failures. Defaults to transport errors (other than the
+// request's context being canceled) and 5xx responses.
+func WithFailureCondition(isFailure func(resp *http.Response, err error) bool) CircuitBreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.isFailure = isFailure
+	}
+}
+
+// WithStateChange registers a callback that is invoked whenever the circuit for a host changes state.
+func WithStateChange(onStateChange func(host string, from, to CircuitState)) CircuitBreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.onStateChange = onStateChange
+	}
+}
+
+// NewCircuitBreaker creates a circuit breaker. use it with [Intercept].
+func NewCircuitBreaker(opts ...CircuitBreakerOption) *CircuitBreaker {
+	cb := &CircuitBreaker{
+		failureRatio:        0.5,
+		minRequests:         10,
+		consecutiveFailures: 5,
+		window:              10 * time.Second,
+		coolDown:            30 * time.Second,
+		halfOpenRequests:    1,
+		isFailure:           isServerFailure,
+		circuits:            make(map[string]*circuit),
+	}
+
+	for _, opt := range opts {
+		opt(cb)
+	}
+
+	return cb
+}
+
+// State returns the current state of the circuit for host.
+func (cb *CircuitBreaker) State(host string) CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if c, ok := cb.circuits[host]; ok {
+		return c.state
+	}
+
+	return CircuitClosed
+}
+
+func (cb *CircuitBreaker) Handle(ctx context.Context, req *http.Request, next Interceptor) (*http.Response, error) {
+	host := req.URL.Host
+
+	probeRound, err := cb.acquire(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := next.Handle(ctx, req, nil)
+	cb.release(ctx, host, probeRound, resp, err)
+
+	return resp, err
+}
+
+// acquire decides whether a request to host may be sent. requests let through as probes get the round of the
+// half-open circuit they're probing, others get 0.
+func (cb *CircuitBreaker) acquire(ctx context.Context, host string) (int, error) {
+	cb.mu.Lock()
+
+	c, ok := cb.circuits[host]
+	if !ok {
+		c = &circuit{
+			requests: newSlidingCounter(cb.window),
+			failures: newSlidingCounter(cb.window),
+		}
+		cb.circuits[host] = c
+	}
+
+	from := c.state
+	if c.state == CircuitOpen && time.Since(c.openedAt) >= cb.coolDown {
+		c.state = CircuitHalfOpen
+		c.probes = 0
+		c.round++
+	}
+
+	var probeRound int
+	var err error
+	switch c.state {
+	case CircuitClosed:
+	case CircuitOpen:
+		err = fmt.Errorf("%w: %s", ErrCircuitOpen, host)
+	case CircuitHalfOpen:
+		if c.probes >= cb.halfOpenRequests {
+			err = fmt.Errorf("%w: %s (waiting on probe requests)", ErrCircuitOpen, host)
+		} else {
+			c.probes++
+			probeRound = c.round
+		}
+	}
+
+	to := c.state
+	cb.mu.Unlock()
+
+	observationFromContext(ctx).annotate(attribute.String("circuit.state", to.String()))
+	cb.transitioned(ctx, host, from, to)
+
+	return probeRound, err
+}
+
+// release records the outcome of a request to host.
+func (cb *CircuitBreaker) release(ctx context.Context, host string, probeRound int, resp *http.Response, err error) {
+	failed := cb.isFailure(resp, err)
+
+	cb.mu.Lock()
+
+	c := cb.circuits[host]
+	from := c.state
+	now := time.Now()
+
+	switch {
+	case probeRound > 0:
+		// probes of an earlier round were already accounted for when the circuit became half-open again
+		if probeRound != c.round {
+			break
+		}
+
+		c.probes--
+
+		// another probe may have decided already, and a canceled probe says nothing about the host, so it only gives
+		// its slot back
+		if c.state != CircuitHalfOpen || errors.Is(err, context.Canceled) {
+			break
+		}
+
+		if failed {
+			cb.open(c, now)
+		} else {
+			c.state = CircuitClosed
+			c.consecutiveFailures = 0
+			c.requests = newSlidingCounter(cb.window)
+			c.failures = newSlidingCounter(cb.window)
+		}
+	case c.state == CircuitClosed:
+		c.requests.add(now)
+		if failed {
+			c.failures.add(now)
+			c.consecutiveFailures++
+		} else {
+			c.consecutiveFailures = 0
+		}
+
+		if failed && cb.shouldTrip(c, now) {
+			cb.open(c, now)
+		}
+	default:
+		// a request that was let through before the circuit opened. nothing left to decide
+	}
+
+	to := c.state
+	cb.mu.Unlock()
+
+	cb.transitioned(ctx, host, from, to)
+}
+
+func (cb *CircuitBreaker) shouldTrip(c *circuit, now time.Time) bool {
+	if cb.consecutiveFailures > 0 && c.consecutiveFailures >= cb.consecutiveFailures {
+		return true
+	}
+
+	requests := c.requests.sum(now)
+	if cb.failureRatio <= 0 || requests < cb.minRequests {
+		return false
+	}
+
+	return float64(c.failures.sum(now))/float64(requests) >= cb.failureRatio
+}
+
+func (cb *CircuitBreaker) open(c *circuit, now time.Time) {
+	c.state = CircuitOpen
+	c.openedAt = now
+	c.consecutiveFailures = 0
+}
+
+func (cb *CircuitBreaker) transitioned(ctx context.Context, host string, from, to CircuitState) {
+	if from == to {
+		return
+	}
+
+	observationFromContext(ctx).recordCircuitTransition(ctx, host, from, to)
+
+	if cb.onStateChange != nil {
+		cb.onStateChange(host, from, to)
+	}
+}
+
+func isServerFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+
+	return resp.StatusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,231 @@
+package httpr
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Encoding is a content coding used to compress HTTP bodies.
+type Encoding string
+
+const (
+	EncodingGzip   Encoding = "gzip"
+	EncodingZstd   Encoding = "zstd"
+	EncodingBrotli Encoding = "br"
+)
+
+func (e Encoding) supported() bool {
+	return e == EncodingGzip || e == EncodingZstd || e == EncodingBrotli
+}
+
+// requestCompressor compresses request bodies as they're sent.
+type requestCompressor struct {
+	encoding Encoding
+	minSize  int64
+}
+
+var _ Interceptor = (*requestCompressor)(nil)
+
+// CompressRequest compresses request bodies with encoding and sets the Content-Encoding header accordingly. bodies
+// are compressed as they're sent, so they're never buffered in memory, except for bodies of unknown length (e.g.
+// streams) whose first minSize bytes are read ahead to determine whether they're large enough. bodies smaller than
+// minSize and requests that already have a Content-Encoding are sent as is.
+func CompressRequest(encoding Encoding, minSize int64) Option {
+	return interceptOption{&requestCompressor{encoding: encoding, minSize: minSize}}
+}
+
+func (c *requestCompressor) Handle(ctx context.Context, req *http.Request, next Interceptor) (*http.Response, error) {
+	if !c.encoding.supported() {
+		return nil, fmt.Errorf("unsupported request encoding %q", c.encoding)
+	}
+
+	if req.Body == nil || req.Body == http.NoBody || req.Header.Get("Content-Encoding") != "" {
+		return next.Handle(ctx, req, nil)
+	}
+
+	switch {
+	case req.ContentLength > 0:
+		if req.ContentLength < c.minSize {
+			return next.Handle(ctx, req, nil)
+		}
+	case c.minSize > 0:
+		// the length of the body is unknown so its beginning is read to find out whether it's large enough. the
+		// buffer only grows as much as the body does, since most of these bodies are probably small
+		var head bytes.Buffer
+		n, err := head.ReadFrom(io.LimitReader(req.Body, c.minSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+
+		if n < c.minSize {
+			req.Body.Close()
+			req.Body = io.NopCloser(&head)
+			req.ContentLength = n
+
+			return next.Handle(ctx, req, nil)
+		}
+
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(&head, req.Body), req.Body}
+	}
+
+	compressed := c.compress(req.Body)
+	req.Body = compressed
+	req.ContentLength = -1
+	req.Header.Set("Content-Encoding", string(c.encoding))
+
+	if getBody := req.GetBody; getBody != nil {
+		req.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+
+			return c.compress(body), nil
+		}
+	}
+
+	resp, err := next.Handle(ctx, req, nil)
+	if err != nil {
+		// stops the compression in case the body was never read, e.g. because a later interceptor rejected the
+		// request
+		compressed.Close()
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// compress returns a reader of the compressed content of body. closing it stops the compression.
+func (c *requestCompressor) compress(body io.ReadCloser) io.ReadCloser {
+	pr, pw := io.Pipe()
+
+	go func() {
+		defer body.Close()
+
+		encoder, err := newEncoder(pw, c.encoding)
+		if err == nil {
+			_, err = io.Copy(encoder, body)
+			if closeErr := encoder.Close(); err == nil {
+				err = closeErr
+			}
+		}
+
+		pw.CloseWithError(err)
+	}()
+
+	return pr
+}
+
+func newEncoder(w io.Writer, encoding Encoding) (io.WriteCloser, error) {
+	switch encoding {
+	case EncodingGzip:
+		return gzip.NewWriter(w), nil
+	case EncodingZstd:
+		encoder, err := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+		}
+
+		return encoder, nil
+	default:
+		return brotli.NewWriter(w), nil
+	}
+}
+
+// responseDecompressor decodes compressed response bodies.
+type responseDecompressor struct{}
+
+var _ Interceptor = responseDecompressor{}
+
+// DecompressResponse asks servers for zstd, brotli or gzip compressed responses, and decodes them as they're read.
+// net/http only does this for gzip, and stops doing it as soon as the Accept-Encoding header is set. requests that
+// already have an Accept-Encoding header are left alone, but their responses are still decoded.
+func DecompressResponse() Option {
+	return interceptOption{responseDecompressor{}}
+}
+
+func (responseDecompressor) Handle(ctx context.Context, req *http.Request, next Interceptor) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", "zstd, br, gzip")
+	}
+
+	resp, err := next.Handle(ctx, req, nil)
+	if err != nil || resp.Body == nil || resp.Body == http.NoBody {
+		return resp, err
+	}
+
+	encoding := Encoding(strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))))
+	if !encoding.supported() {
+		return resp, nil
+	}
+
+	resp.Body = &decodedBody{body: resp.Body, encoding: encoding}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+
+	return resp, nil
+}
+
+// decodedBody decodes the body it wraps. the decoder is created on the first read, since some read ahead.
+type decodedBody struct {
+	body     io.ReadCloser
+	encoding Encoding
+
+	once    sync.Once
+	decoder io.Reader
+	close   func()
+	err     error
+}
+
+func (d *decodedBody) Read(b []byte) (int, error) {
+	d.once.Do(func() {
+		switch d.encoding {
+		case EncodingGzip:
+			decoder, err := gzip.NewReader(d.body)
+			d.decoder, d.err = decoder, err
+		case EncodingZstd:
+			decoder, err := zstd.NewReader(d.body, zstd.WithDecoderConcurrency(1))
+			if err == nil {
+				d.decoder, d.close = decoder, decoder.Close
+			}
+
+			d.err = err
+		case EncodingBrotli:
+			d.decoder = brotli.NewReader(d.body)
+		}
+
+		// an empty body is just empty
+		if d.err != nil && !errors.Is(d.err, io.EOF) {
+			d.err = fmt.Errorf("failed to decode %s response body: %w", d.encoding, d.err)
+		}
+	})
+
+	if d.err != nil {
+		return 0, d.err
+	}
+
+	return d.decoder.Read(b)
+}
+
+func (d *decodedBody) Close() error {
+	if d.close != nil {
+		d.close()
+	}
+
+	return d.body.Close()
+}
@@ -0,0 +1,222 @@
+package httpr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrConcurrencyLimit matches every [ConcurrencyLimitError] when used with errors.Is.
+var ErrConcurrencyLimit = errors.New("concurrency limit reached")
+
+// ConcurrencyLimitError is returned when a request is rejected by a [ConcurrencyLimiter], either because the queue
+// was full or because the request waited in the queue for too long.
+type ConcurrencyLimitError struct {
+	// Host is the host whose limit was reached. empty if the global limit was reached.
+	Host string
+	// QueueFull is true if the request was rejected without waiting because the queue was full.
+	QueueFull bool
+}
+
+func (e *ConcurrencyLimitError) Error() string {
+	limit := "global"
+	if e.Host != "" {
+		limit = e.Host
+	}
+
+	if e.QueueFull {
+		return fmt.Sprintf("%s: queue for %s is full", ErrConcurrencyLimit, limit)
+	}
+
+	return fmt.Sprintf("%s: timed out waiting in queue for %s", ErrConcurrencyLimit, limit)
+}
+
+func (e *ConcurrencyLimitError) Is(target error) bool {
+	return target == ErrConcurrencyLimit
+}
+
+// ConcurrencyLimiter is an interceptor that caps the number of in-flight requests per host and, optionally,
+// across all hosts. requests over the limit wait in a bounded queue. a request is in flight until its response
+// body is closed.
+type ConcurrencyLimiter struct {
+	perHost      int
+	global       int
+	queueSize    int
+	queueTimeout time.Duration
+
+	mu       sync.Mutex
+	hosts    map[string]*bulkhead
+	allHosts *bulkhead
+}
+
+var _ Interceptor = (*ConcurrencyLimiter)(nil)
+
+type ConcurrencyOption func(*ConcurrencyLimiter)
+
+// WithGlobalLimit caps the number of in-flight requests across all hosts.
+func WithGlobalLimit(n int) ConcurrencyOption {
+	return func(c *ConcurrencyLimiter) {
+		c.global = n
+	}
+}
+
+// WithQueueSize sets how many requests may wait for a slot, per host and globally. requests beyond that are
+// rejected right away. Defaults to 100.
+func WithQueueSize(n int) ConcurrencyOption {
+	return func(c *ConcurrencyLimiter) {
+		c.queueSize = n
+	}
+}
+
+// WithQueueTimeout bounds how long a request waits for a slot. Defaults to no bound other than the request's context.
+func WithQueueTimeout(d time.Duration) ConcurrencyOption {
+	return func(c *ConcurrencyLimiter) {
+		c.queueTimeout = d
+	}
+}
+
+// ConcurrencyLimit creates an interceptor that allows at most perHost requests to be in flight to each host.
+// a perHost of 0 leaves hosts unlimited, which is useful together with [WithGlobalLimit]. use it with [Intercept].
+func ConcurrencyLimit(perHost int, opts ...ConcurrencyOption) *ConcurrencyLimiter {
+	c := &ConcurrencyLimiter{
+		perHost:   perHost,
+		queueSize: 100,
+		hosts:     make(map[string]*bulkhead),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.global > 0 {
+		c.allHosts = newBulkhead("", c.global, c.queueSize)
+	}
+
+	return c
+}
+
+func (c *ConcurrencyLimiter) Handle(ctx context.Context, req *http.Request, next Interceptor) (*http.Response, error) {
+	host := c.bulkhead(req.URL.Host)
+
+	if err := host.acquire(ctx, c.queueTimeout); err != nil {
+		return nil, err
+	}
+
+	if err := c.allHosts.acquire(ctx, c.queueTimeout); err != nil {
+		host.release(ctx)
+		return nil, err
+	}
+
+	release := func() {
+		c.allHosts.release(ctx)
+		host.release(ctx)
+	}
+
+	resp, err := next.Handle(ctx, req, nil)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	resp.Body = onClose(resp.Body, release)
+
+	return resp, nil
+}
+
+func (c *ConcurrencyLimiter) bulkhead(host string) *bulkhead {
+	if c.perHost <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.hosts[host]
+	if !ok {
+		b = newBulkhead(host, c.perHost, c.queueSize)
+		c.hosts[host] = b
+	}
+
+	return b
+}
+
+// bulkhead is a semaphore with a bounded queue. a nil bulkhead never limits anything.
+type bulkhead struct {
+	host      string
+	slots     chan struct{}
+	queueSize int
+
+	mu     sync.Mutex
+	queued int
+}
+
+func newBulkhead(host string, limit int, queueSize int) *bulkhead {
+	return &bulkhead{
+		host:      host,
+		slots:     make(chan struct{}, limit),
+		queueSize: queueSize,
+	}
+}
+
+func (b *bulkhead) acquire(ctx context.Context, timeout time.Duration) error {
+	if b == nil {
+		return nil
+	}
+
+	obs := observationFromContext(ctx)
+
+	select {
+	case b.slots <- struct{}{}:
+		obs.recordInflight(ctx, b.host, 1)
+		return nil
+	default:
+	}
+
+	b.mu.Lock()
+	if b.queued >= b.queueSize {
+		b.mu.Unlock()
+		return &ConcurrencyLimitError{Host: b.host, QueueFull: true}
+	}
+	b.queued++
+	b.mu.Unlock()
+
+	obs.recordQueued(ctx, b.host, 1)
+
+	defer func() {
+		b.mu.Lock()
+		b.queued--
+		b.mu.Unlock()
+
+		obs.recordQueued(ctx, b.host, -1)
+	}()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		expired = timer.C
+	}
+
+	select {
+	case b.slots <- struct{}{}:
+		obs.recordInflight(ctx, b.host, 1)
+		return nil
+	case <-expired:
+		return &ConcurrencyLimitError{Host: b.host}
+	case <-ctx.Done():
+		return fmt.Errorf("interrupted while waiting for a concurrency slot: %w", context.Cause(ctx))
+	}
+}
+
+func (b *bulkhead) release(ctx context.Context) {
+	if b == nil {
+		return
+	}
+
+	<-b.slots
+	observationFromContext(ctx).recordInflight(ctx, b.host, -1)
+}
@@ -0,0 +1,198 @@
+package httpr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// deduper collapses identical GET and HEAD requests that are in flight at the same time into a single upstream
+// request. every caller gets its own copy of the response.
+type deduper struct {
+	headers []string
+
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight is a request that is in flight on behalf of one or more callers.
+type flight struct {
+	done chan struct{}
+	// cancel cancels the upstream request, once every caller has given up on it or has read its response
+	cancel context.CancelFunc
+	// waiters is the number of callers waiting for the response
+	waiters int
+
+	resp *http.Response
+	err  error
+	// shared is set when the response is shared by several callers, in which case its body is read into memory.
+	// otherwise, resp is handed as is to the only caller left.
+	shared bool
+	body   []byte
+}
+
+type dedupeOption struct {
+	headers []string
+}
+
+// Client installs the deduper at the end of the chain, right before the request is sent, so that requests are keyed
+// exactly as they're sent, including credentials added by interceptors and signers.
+func (d dedupeOption) Client(c *Client) {
+	c.deduper = &deduper{
+		headers: d.headers,
+		flights: make(map[string]*flight),
+	}
+}
+
+// Dedupe collapses identical GET and HEAD requests sent by the client at the same time into a single upstream
+// request. requests are identical if their method, URL, Authorization header and the values of the provided headers
+// (e.g. Accept) match as they're sent, i.e. after every interceptor and signer ran. every caller gets an independent
+// copy of the response, which is read into memory if it's shared by several callers. the upstream request is only
+// cancelled once every caller waiting on it has given up.
+func Dedupe(headers ...string) ClientOption {
+	canonical := []string{"Authorization"}
+	for _, header := range headers {
+		if header = http.CanonicalHeaderKey(header); !slices.Contains(canonical, header) {
+			canonical = append(canonical, header)
+		}
+	}
+
+	return dedupeOption{headers: canonical}
+}
+
+func (d *deduper) Handle(ctx context.Context, req *http.Request, next Interceptor) (*http.Response, error) {
+	// hedges are duplicates by design, collapsing them into the request they're racing would defeat their purpose
+	hedged, _ := ctx.Value(hedgedKey{}).(bool)
+	if hedged || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		return next.Handle(ctx, req, nil)
+	}
+
+	key := d.key(req)
+
+	d.mu.Lock()
+	f, ok := d.flights[key]
+	if ok {
+		f.waiters++
+		observationFromContext(ctx).annotate(attribute.Bool("dedupe.shared", true))
+	} else {
+		// the upstream request is sent on behalf of every caller, so it can't be cancelled by any single one of them
+		flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), cancel: cancel, waiters: 1}
+		d.flights[key] = f
+
+		go d.send(flightCtx, key, f, req.WithContext(flightCtx), next)
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.response()
+	case <-ctx.Done():
+		d.leave(f)
+		return nil, context.Cause(ctx)
+	}
+}
+
+// send sends the upstream request of f and hands its response over to the callers waiting for it.
+func (d *deduper) send(ctx context.Context, key string, f *flight, req *http.Request, next Interceptor) {
+	resp, err := next.Handle(ctx, req, nil)
+
+	// callers that come after this point send a request of their own since the response may not be buffered
+	d.mu.Lock()
+	delete(d.flights, key)
+
+	f.resp, f.err = resp, err
+	f.shared = f.waiters > 1
+	if !f.shared {
+		switch {
+		case err != nil:
+			f.cancel()
+		case f.waiters == 0:
+			discardResponse(resp)
+			f.cancel()
+		default:
+			resp.Body = onClose(resp.Body, f.cancel)
+		}
+
+		// done is closed while holding the lock so that a caller leaving at the same time knows whether the
+		// response was handed to it
+		close(f.done)
+		d.mu.Unlock()
+
+		return
+	}
+	d.mu.Unlock()
+
+	if err == nil {
+		f.body, f.err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if f.err != nil {
+			f.err = fmt.Errorf("failed to read response body: %w", f.err)
+		}
+	}
+
+	f.cancel()
+	close(f.done)
+}
+
+// leave is called when a caller gives up waiting for f.
+func (d *deduper) leave(f *flight) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	f.waiters--
+
+	select {
+	case <-f.done:
+		// the response was handed to this caller only, so nobody else is going to close it
+		if !f.shared {
+			discardResponse(f.resp)
+		}
+	default:
+		if f.waiters == 0 {
+			f.cancel()
+		}
+	}
+}
+
+func (d *deduper) key(req *http.Request) string {
+	var key strings.Builder
+	key.WriteString(req.Method)
+	key.WriteString(" ")
+	key.WriteString(req.URL.String())
+
+	for _, header := range d.headers {
+		key.WriteString("\n")
+		key.WriteString(header)
+		key.WriteString(": ")
+		key.WriteString(strings.Join(req.Header.Values(header), ","))
+	}
+
+	return key.String()
+}
+
+// response returns a copy of the flight's response that can be read and modified independently of other copies.
+func (f *flight) response() (*http.Response, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	if !f.shared {
+		return f.resp, nil
+	}
+
+	resp := *f.resp
+	resp.Header = f.resp.Header.Clone()
+	resp.Trailer = f.resp.Trailer.Clone()
+	resp.Body = io.NopCloser(bytes.NewReader(f.body))
+
+	return &resp, nil
+}
@@ -0,0 +1,258 @@
+package httpr
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec // required by RFC 7616
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// digestAuth answers HTTP Digest authentication challenges (RFC 7616). once challenged, it keeps authorizing
+// requests to the same origin with the same nonce, counting its uses, until the server issues a new one.
+type digestAuth struct {
+	username string
+	password string
+
+	mu sync.Mutex
+	// challenges holds the last challenge of every origin (scheme and host), along with its realm, so that
+	// credentials are only ever sent to the protection space that asked for them
+	challenges map[string]*digestChallenge
+}
+
+var _ Interceptor = (*digestAuth)(nil)
+
+// digestChallenge is the content of a WWW-Authenticate: Digest header.
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string
+	userhash  bool
+	// count is the number of requests sent with this nonce
+	count uint32
+}
+
+// DigestAuth authenticates requests with HTTP Digest authentication (RFC 7616). requests are sent without
+// credentials until the server responds with a 401 challenge, after which they're sent again with credentials.
+// subsequent requests to the same origin are authorized right away. supports the MD5 and SHA-256 algorithms (and
+// their -sess variants) with qop=auth.
+func DigestAuth(username, password string) Option {
+	return interceptOption{&digestAuth{
+		username:   username,
+		password:   password,
+		challenges: make(map[string]*digestChallenge),
+	}}
+}
+
+func (d *digestAuth) Handle(ctx context.Context, req *http.Request, next Interceptor) (*http.Response, error) {
+	if authorization, ok := d.authorize(req); ok {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := next.Handle(ctx, req, nil)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	challenge, ok := parseDigestChallenge(resp.Header)
+	if !ok {
+		return resp, nil
+	}
+
+	// the 401 is returned as is if the request can't be sent again
+	replay, err := rewindRequest(req)
+	if err != nil {
+		return resp, nil //nolint:nilerr // the caller gets the server's response
+	}
+
+	d.mu.Lock()
+	d.challenges[digestOrigin(req)] = challenge
+	d.mu.Unlock()
+
+	authorization, _ := d.authorize(replay)
+	replay.Header.Set("Authorization", authorization)
+
+	discardResponse(resp)
+
+	return next.Handle(ctx, replay, nil)
+}
+
+// authorize computes the Authorization header for req based on the last challenge of its origin, if any.
+func (d *digestAuth) authorize(req *http.Request) (string, bool) {
+	d.mu.Lock()
+	challenge := d.challenges[digestOrigin(req)]
+	if challenge == nil {
+		d.mu.Unlock()
+		return "", false
+	}
+
+	challenge.count++
+	count := challenge.count
+	d.mu.Unlock()
+
+	h := challenge.hash()
+	digest := func(parts ...string) string {
+		h.Reset()
+		h.Write([]byte(strings.Join(parts, ":")))
+
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	cnonce := make([]byte, 16)
+	_, _ = rand.Read(cnonce)
+	clientNonce := hex.EncodeToString(cnonce)
+	nc := fmt.Sprintf("%08x", count)
+	uri := req.URL.RequestURI()
+
+	ha1 := digest(d.username, challenge.realm, d.password)
+	if strings.HasSuffix(strings.ToLower(challenge.algorithm), "-sess") {
+		ha1 = digest(ha1, challenge.nonce, clientNonce)
+	}
+
+	ha2 := digest(req.Method, uri)
+
+	username := d.username
+	if challenge.userhash {
+		username = digest(d.username, challenge.realm)
+	}
+
+	params := []string{
+		"username=" + sfString(username),
+		"realm=" + sfString(challenge.realm),
+		"nonce=" + sfString(challenge.nonce),
+		"uri=" + sfString(uri),
+		"algorithm=" + challenge.algorithm,
+	}
+
+	if challenge.qop == "" {
+		// RFC 2069 compatibility
+		params = append(params, "response="+sfString(digest(ha1, challenge.nonce, ha2)))
+	} else {
+		params = append(params,
+			"response="+sfString(digest(ha1, challenge.nonce, nc, clientNonce, challenge.qop, ha2)),
+			"qop="+challenge.qop,
+			"nc="+nc,
+			"cnonce="+sfString(clientNonce),
+		)
+	}
+
+	if challenge.opaque != "" {
+		params = append(params, "opaque="+sfString(challenge.opaque))
+	}
+
+	if challenge.userhash {
+		params = append(params, "userhash=true")
+	}
+
+	return "Digest " + strings.Join(params, ", "), true
+}
+
+// digestOrigin returns the scheme and host req is sent to, which together with the realm of a challenge make up its
+// protection space.
+func digestOrigin(req *http.Request) string {
+	return strings.ToLower(req.URL.Scheme + "://" + req.URL.Host)
+}
+
+func (c *digestChallenge) hash() hash.Hash {
+	if strings.HasPrefix(strings.ToUpper(c.algorithm), "SHA-256") {
+		return sha256.New()
+	}
+
+	return md5.New() //nolint:gosec // required by RFC 7616
+}
+
+// parseDigestChallenge finds a supported Digest challenge among the WWW-Authenticate headers.
+func parseDigestChallenge(header http.Header) (*digestChallenge, bool) {
+	for _, value := range header.Values("Www-Authenticate") {
+		scheme, rest, _ := strings.Cut(strings.TrimSpace(value), " ")
+		if !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+
+		params := parseAuthParams(rest)
+
+		challenge := &digestChallenge{
+			realm:     params["realm"],
+			nonce:     params["nonce"],
+			opaque:    params["opaque"],
+			algorithm: params["algorithm"],
+			userhash:  strings.EqualFold(params["userhash"], "true"),
+		}
+
+		if challenge.algorithm == "" {
+			challenge.algorithm = "MD5"
+		}
+
+		switch strings.ToUpper(challenge.algorithm) {
+		case "MD5", "MD5-SESS", "SHA-256", "SHA-256-SESS":
+		default:
+			continue
+		}
+
+		if qop, ok := params["qop"]; ok {
+			for _, option := range strings.Split(qop, ",") {
+				if strings.TrimSpace(option) == "auth" {
+					challenge.qop = "auth"
+				}
+			}
+
+			// auth-int isn't supported
+			if challenge.qop == "" {
+				continue
+			}
+		}
+
+		if challenge.nonce != "" {
+			return challenge, true
+		}
+	}
+
+	return nil, false
+}
+
+// parseAuthParams parses the comma separated name=value pairs of an authentication challenge. values may be
+// quoted.
+func parseAuthParams(s string) map[string]string {
+	params := map[string]string{}
+	for s != "" {
+		s = strings.TrimLeft(s, " \t,")
+
+		name, rest, found := strings.Cut(s, "=")
+		if !found {
+			break
+		}
+
+		name = strings.ToLower(strings.TrimSpace(name))
+		rest = strings.TrimLeft(rest, " \t")
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+
+				b.WriteByte(rest[i])
+			}
+
+			value = b.String()
+			s = rest[min(i+1, len(rest)):]
+		} else {
+			value, s, _ = strings.Cut(rest, ",")
+			value = strings.TrimSpace(value)
+		}
+
+		params[name] = value
+	}
+
+	return params
+}
@@ -48,10 +48,50 @@ export default defineConfig({
 					label: 'Response Body',
 					link: '/response-body'
 				},
+				{
+					label: 'Compression',
+					link: '/compression'
+				},
 				{
 					label: 'Interceptors',
 					link: '/interceptors'
 				},
+				{
+					label: 'Authentication',
+					link: '/authentication'
+				},
+				{
+					label: 'Request Signing',
+					link: '/signing'
+				},
+				{
+					label: 'Timeouts',
+					link: '/timeouts'
+				},
+				{
+					label: 'Retries',
+					link: '/retries'
+				},
+				{
+					label: 'Circuit Breaking',
+					link: '/circuit-breaker'
+				},
+				{
+					label: 'Hedged Requests',
+					link: '/hedging'
+				},
+				{
+					label: 'Rate Limiting',
+					link: '/rate-limiting'
+				},
+				{
+					label: 'Request Deduplication',
+					link: '/deduplication'
+				},
+				{
+					label: 'Caching',
+					link: '/caching'
+				},
 				{
 					label: 'Observability',
 					link: '/observability'
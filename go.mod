@@ -5,7 +5,9 @@ go 1.23.4
 require (
 	github.com/alecthomas/assert/v2 v2.10.0
 	github.com/alecthomas/types v0.16.0
+	github.com/andybalholm/brotli v1.1.1
 	github.com/jarcoal/httpmock v1.3.1
+	github.com/klauspost/compress v1.17.11
 	go.opentelemetry.io/otel v1.31.0
 	go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc v1.31.0
 	go.opentelemetry.io/otel/metric v1.31.0
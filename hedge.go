@@ -0,0 +1,155 @@
+package httpr
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type hedgeKey struct{}
+
+// hedgedKey marks the context of duplicate requests sent by a [Hedger].
+type hedgedKey struct{}
+
+// Hedger is an interceptor that sends duplicate requests when the first one is slow to respond and returns
+// whichever response arrives first. losing requests are cancelled and their bodies closed.
+type Hedger struct {
+	delay     time.Duration
+	maxHedges int
+}
+
+var _ Interceptor = (*Hedger)(nil)
+
+// Hedge creates an interceptor that fires up to maxHedges duplicate requests, one every delay, for as long as no
+// response has been received. only idempotent requests (GET, HEAD, OPTIONS, TRACE, PUT, DELETE) are hedged unless
+// [AllowHedge] is provided for the request. use it with [Intercept].
+func Hedge(delay time.Duration, maxHedges int) *Hedger {
+	return &Hedger{delay: delay, maxHedges: maxHedges}
+}
+
+type allowHedgeOption struct{}
+
+func (allowHedgeOption) Request(r *requestOptions) {
+	r.allowHedge = true
+}
+
+// AllowHedge opts a request with a non-idempotent method (e.g. POST) into hedging. only use it for requests that
+// are safe to send more than once.
+func AllowHedge() RequestOption {
+	return allowHedgeOption{}
+}
+
+type hedgeResult struct {
+	resp    *http.Response
+	err     error
+	attempt int
+}
+
+func (h *Hedger) Handle(ctx context.Context, req *http.Request, next Interceptor) (*http.Response, error) {
+	allowed, _ := ctx.Value(hedgeKey{}).(bool)
+	if h.maxHedges < 1 || (!isIdempotent(req.Method) && !allowed) {
+		return next.Handle(ctx, req, nil)
+	}
+
+	results := make(chan hedgeResult, h.maxHedges+1)
+	cancels := make([]context.CancelFunc, 0, h.maxHedges+1)
+
+	launch := func(r *http.Request) {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		attempt := len(cancels)
+		cancels = append(cancels, cancel)
+
+		if attempt > 0 {
+			attemptCtx = context.WithValue(attemptCtx, hedgedKey{}, true)
+		}
+
+		go func() {
+			resp, err := next.Handle(attemptCtx, r.WithContext(attemptCtx), nil)
+			results <- hedgeResult{resp: resp, err: err, attempt: attempt}
+		}()
+	}
+
+	// cancels the requests still in flight, except for the winner, and cleans up after them once they return
+	abandon := func(inflight int, winner int) {
+		for attempt, cancel := range cancels {
+			if attempt != winner {
+				cancel()
+			}
+		}
+
+		go func() {
+			for range inflight {
+				discardResponse((<-results).resp)
+			}
+		}()
+	}
+
+	// every attempt gets a copy of its own since the interceptors after this one may change it (e.g. set headers)
+	// while the next copy is being taken. the first one keeps the original body since it hasn't been read yet
+	launch(req.Clone(ctx))
+	inflight := 1
+
+	timer := time.NewTimer(h.delay)
+	defer timer.Stop()
+
+	var lastErr error
+	for {
+		select {
+		case result := <-results:
+			inflight--
+			if result.err == nil {
+				observationFromContext(ctx).annotate(attribute.Int("hedge.requests", len(cancels)))
+
+				// the winner's context has to outlive this call so that its body can still be read
+				abandon(inflight, result.attempt)
+				result.resp.Body = onClose(result.resp.Body, cancels[result.attempt])
+
+				return result.resp, nil
+			}
+
+			lastErr = result.err
+			if inflight > 0 {
+				continue
+			}
+
+			// every request sent so far failed. hedge right away rather than waiting out the delay
+			if len(cancels) > h.maxHedges || !h.hedge(req, launch) {
+				abandon(0, -1)
+				return nil, lastErr
+			}
+
+			inflight++
+		case <-timer.C:
+			if len(cancels) <= h.maxHedges && h.hedge(req, launch) {
+				inflight++
+				timer.Reset(h.delay)
+			}
+		case <-ctx.Done():
+			abandon(inflight, -1)
+			return nil, context.Cause(ctx)
+		}
+	}
+}
+
+// hedge sends a copy of req. it reports false if the request's body can't be sent again.
+func (h *Hedger) hedge(req *http.Request, launch func(*http.Request)) bool {
+	hedgeReq, err := rewindRequest(req)
+	if err != nil {
+		return false
+	}
+
+	launch(hedgeReq)
+
+	return true
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,194 @@
+package httpr
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultHMACTemplate is the canonical string signed by [HMACSigner] unless configured otherwise.
+const DefaultHMACTemplate = "{{.Method}}\n{{.URI}}\n{{.Timestamp}}\n{{.Nonce}}\n{{.Body}}"
+
+// HMACMessage is what's known about a request when building the canonical string to sign.
+type HMACMessage struct {
+	Method string
+	// URI is the path and query of the request, e.g. /v1/orders?status=open.
+	URI   string
+	Path  string
+	Query string
+	Host  string
+	// Timestamp is the value of the timestamp header, or empty if it's disabled.
+	Timestamp string
+	// Nonce is the value of the nonce header, or empty if it's disabled.
+	Nonce  string
+	Body   string
+	Header http.Header
+}
+
+// HMACSigner signs requests with an HMAC of a canonical string built from the request, as required by many
+// webhook-style APIs. use it with [Sign].
+type HMACSigner struct {
+	key             []byte
+	hash            func() hash.Hash
+	header          string
+	prefix          string
+	timestampHeader string
+	nonceHeader     string
+	encode          func([]byte) string
+	canonical       func(msg HMACMessage) (string, error)
+}
+
+var _ Interceptor = (*HMACSigner)(nil)
+
+type HMACOption func(*HMACSigner)
+
+// WithSignatureHeader sets the header that carries the signature and a prefix for its value (e.g. "sha256=").
+// Defaults to X-Signature without a prefix.
+func WithSignatureHeader(name, prefix string) HMACOption {
+	return func(s *HMACSigner) {
+		s.header = name
+		s.prefix = prefix
+	}
+}
+
+// WithTimestampHeader sets the header that carries the time of signing, in seconds since the Unix epoch. an empty
+// name disables it. Defaults to X-Timestamp.
+func WithTimestampHeader(name string) HMACOption {
+	return func(s *HMACSigner) {
+		s.timestampHeader = name
+	}
+}
+
+// WithNonceHeader sets the header that carries a random nonce, unique to every request. an empty name disables it.
+// Defaults to X-Nonce.
+func WithNonceHeader(name string) HMACOption {
+	return func(s *HMACSigner) {
+		s.nonceHeader = name
+	}
+}
+
+// WithHMACHash sets the hash function used for the HMAC. Defaults to SHA-256.
+func WithHMACHash(h func() hash.Hash) HMACOption {
+	return func(s *HMACSigner) {
+		s.hash = h
+	}
+}
+
+// WithBase64Signature encodes the signature with standard base64 instead of hex.
+func WithBase64Signature() HMACOption {
+	return func(s *HMACSigner) {
+		s.encode = base64.StdEncoding.EncodeToString
+	}
+}
+
+// WithCanonicalTemplate builds the canonical string from a text/template executed with an [HMACMessage], e.g.
+// "{{.Timestamp}}.{{.Body}}". Defaults to [DefaultHMACTemplate]. if the template can't be parsed, signing fails.
+func WithCanonicalTemplate(text string) HMACOption {
+	tmpl, err := template.New("hmac").Parse(text)
+
+	return func(s *HMACSigner) {
+		s.canonical = func(msg HMACMessage) (string, error) {
+			if err != nil {
+				return "", fmt.Errorf("invalid canonical template: %w", err)
+			}
+
+			var b strings.Builder
+			if err := tmpl.Execute(&b, msg); err != nil {
+				return "", fmt.Errorf("failed to build canonical string: %w", err)
+			}
+
+			return b.String(), nil
+		}
+	}
+}
+
+// WithCanonicalFunc builds the canonical string with a function, for formats that can't be expressed as a template.
+func WithCanonicalFunc(canonical func(msg HMACMessage) string) HMACOption {
+	return func(s *HMACSigner) {
+		s.canonical = func(msg HMACMessage) (string, error) {
+			return canonical(msg), nil
+		}
+	}
+}
+
+// HMACSign creates a signer that signs requests with key.
+func HMACSign(key []byte, opts ...HMACOption) *HMACSigner {
+	s := &HMACSigner{
+		key:             key,
+		hash:            sha256.New,
+		header:          "X-Signature",
+		timestampHeader: "X-Timestamp",
+		nonceHeader:     "X-Nonce",
+		encode:          hex.EncodeToString,
+	}
+
+	WithCanonicalTemplate(DefaultHMACTemplate)(s)
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func (s *HMACSigner) Handle(ctx context.Context, req *http.Request, next Interceptor) (*http.Response, error) {
+	msg := HMACMessage{
+		Method: req.Method,
+		URI:    req.URL.RequestURI(),
+		Path:   req.URL.EscapedPath(),
+		Query:  req.URL.RawQuery,
+		Host:   requestHost(req),
+	}
+
+	if s.timestampHeader != "" {
+		msg.Timestamp = strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(s.timestampHeader, msg.Timestamp)
+	}
+
+	if s.nonceHeader != "" {
+		nonce := make([]byte, 16)
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, fmt.Errorf("failed to generate nonce: %w", err)
+		}
+
+		msg.Nonce = hex.EncodeToString(nonce)
+		req.Header.Set(s.nonceHeader, msg.Nonce)
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body for signing: %w", err)
+		}
+
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+		msg.Body = string(body)
+	}
+
+	msg.Header = req.Header
+
+	canonical, err := s.canonical(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(s.hash, s.key)
+	mac.Write([]byte(canonical))
+	req.Header.Set(s.header, s.prefix+s.encode(mac.Sum(nil)))
+
+	return next.Handle(ctx, req, nil)
+}
@@ -2,11 +2,14 @@ package httpr
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"maps"
 	"net/http"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/alecthomas/types/optional"
 )
@@ -16,8 +19,14 @@ type Client struct {
 	baseURL             optional.Option[string]
 	headers             map[string]string
 	interceptors        []Interceptor
+	signers             []Interceptor
 	requestBodyHandler  optional.Option[requestBodyHandler]
 	responseBodyHandler optional.Option[responseBodyHandler]
+	errBody             any
+	errorOnStatus       func(statusCode int) bool
+	retryBudget         *retryBudget
+	attemptTimeout      time.Duration
+	deduper             *deduper
 }
 
 func NewClient(options ...ClientOption) *Client {
@@ -55,22 +64,68 @@ func (c *Client) Delete(ctx context.Context, url string, options ...RequestOptio
 // SendRequest sends a request to the specified URL with the specified method and options.
 func (c *Client) SendRequest(ctx context.Context, method string, path string, options ...RequestOption) (resp *http.Response, err error) {
 	opts := requestOptions{
-		requestBody:  c.requestBodyHandler,
-		responseBody: c.responseBodyHandler,
-		headers:      maps.Clone(c.headers),
-		interceptors: c.interceptors,
+		requestBody:    c.requestBodyHandler,
+		responseBody:   c.responseBodyHandler,
+		errBody:        c.errBody,
+		errorOnStatus:  c.errorOnStatus,
+		headers:        maps.Clone(c.headers),
+		interceptors:   c.interceptors,
+		signers:        c.signers,
+		attemptTimeout: c.attemptTimeout,
 	}
 
 	for _, option := range options {
 		option.Request(&opts)
 	}
 
+	var cancelTimeout context.CancelFunc
+	if opts.timeout > 0 {
+		ctx, cancelTimeout = context.WithTimeoutCause(ctx, opts.timeout, ErrRequestTimeout)
+
+		// on success, the deadline is released once the response body is closed (see below)
+		defer func() {
+			if err == nil {
+				return
+			}
+
+			// interceptors that were waiting (e.g. for a retry or a concurrency slot) return the cause of the
+			// timeout only, while errors from the transport only match context.DeadlineExceeded
+			if errors.Is(context.Cause(ctx), ErrRequestTimeout) {
+				if !errors.Is(err, ErrRequestTimeout) {
+					err = fmt.Errorf("%w: %w", ErrRequestTimeout, err)
+				}
+
+				if !errors.Is(err, context.DeadlineExceeded) {
+					err = fmt.Errorf("%w: %w", err, context.DeadlineExceeded)
+				}
+			}
+			cancelTimeout()
+		}()
+	}
+
+	if opts.attemptTimeout > 0 {
+		ctx = context.WithValue(ctx, attemptTimeoutKey{}, opts.attemptTimeout)
+	}
+
+	if opts.allowHedge {
+		ctx = context.WithValue(ctx, hedgeKey{}, true)
+	}
+
+	if opts.uploadProgress != nil {
+		ctx = context.WithValue(ctx, uploadProgressKey{}, opts.uploadProgress)
+	}
+
+	if c.retryBudget != nil {
+		c.retryBudget.deposit()
+		ctx = context.WithValue(ctx, retryBudgetKey{}, c.retryBudget)
+	}
+
 	var bodyReader io.Reader
 	if requestBodyHandler, ok := opts.requestBody.Get(); ok {
 		var contentType string
 		var err error
 
-		bodyReader, contentType, err = requestBodyHandler()
+		bodyReader, contentType, err = requestBodyHandler.open()
 		if err != nil {
 			return nil, fmt.Errorf("failed to get request body: %w", err)
 		}
@@ -93,33 +148,143 @@ func (c *Client) SendRequest(ctx context.Context, method string, path string, op
 
 	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
 	if err != nil {
+		// streamed bodies (e.g. multipart forms) are written by a goroutine that only stops once the body is closed
+		if closer, ok := bodyReader.(io.Closer); ok {
+			closer.Close()
+		}
+
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
+	// net/http only knows the length of a handful of reader types
+	if section, ok := bodyReader.(*io.SectionReader); ok && section.Size() > 0 {
+		req.ContentLength = section.Size()
+	}
+
 	for key, value := range opts.headers {
 		req.Header.Add(key, value)
 	}
 
-	chain := Chain(append(opts.interceptors, c.do())...)
+	// allows the request to be sent more than once (e.g. retries, redirects) by producing a fresh body each time.
+	// bodies that can't be rewound keep whatever net/http made of them, so that e.g. redirects that need the body
+	// again are returned rather than failing
+	if requestBodyHandler, ok := opts.requestBody.Get(); ok && bodyReader != nil &&
+		(requestBodyHandler.rewindable == nil || requestBodyHandler.rewindable()) {
+		req.GetBody = func() (io.ReadCloser, error) {
+			body, _, err := requestBodyHandler.open()
+			if err != nil {
+				return nil, err
+			}
+
+			if rc, ok := body.(io.ReadCloser); ok {
+				return rc, nil
+			}
+
+			return io.NopCloser(body), nil
+		}
+	}
+
+	// signers run after every other interceptor so that they sign the request exactly as it's sent. the deduper comes
+	// last for the same reason, so that credentials added by any interceptor are part of its key
+	interceptors := slices.Concat(opts.interceptors, opts.signers)
+	if c.deduper != nil {
+		interceptors = append(interceptors, c.deduper)
+	}
+	interceptors = append(interceptors, c.do())
+	chain := Chain(interceptors...)
+
+	// lets interceptors send requests of their own through the whole chain (e.g. background cache refreshes)
+	ctx = context.WithValue(ctx, chainKey{}, chain)
+	req = req.WithContext(ctx)
+
 	httpResponse, err := chain.Handle(ctx, req, nil)
 	if err != nil {
+		// the body is only closed by the transport, which an interceptor may not have let the request reach (e.g. an
+		// open circuit)
+		closeRequestBody(req)
+
 		return nil, fmt.Errorf("failed to handle request: %w", err)
 	}
 
+	// the deadline has to outlive this call so that the caller can still read the response body. it's tied to the
+	// body before any handler reads it, since handlers close the body once they're done with it
+	if cancelTimeout != nil {
+		httpResponse.Body = onClose(httpResponse.Body, cancelTimeout)
+	}
+
+	if opts.downloadProgress != nil && httpResponse.Body != http.NoBody {
+		httpResponse.Body = opts.downloadProgress.track(httpResponse.Body, httpResponse.ContentLength)
+	}
+
+	var statusErr *StatusError
+	if opts.errorOnStatus != nil && opts.errorOnStatus(httpResponse.StatusCode) {
+		if statusErr, err = statusError(req, httpResponse); err != nil {
+			discardResponse(httpResponse)
+			return nil, err
+		}
+	}
+
+	var handlerErr error
 	if responseBodyHandler, ok := opts.responseBody.Get(); ok {
-		err := responseBodyHandler(httpResponse)
-		if err != nil {
-			return nil, fmt.Errorf("failed to handle response body: %w", err)
+		if err := responseBodyHandler(httpResponse); err != nil {
+			handlerErr = fmt.Errorf("failed to handle response body: %w", err)
+		}
+	}
+
+	if statusErr != nil {
+		// the response isn't returned, so it's closed here in case no handler read it
+		discardResponse(httpResponse)
+
+		// error bodies often aren't what the handler expects (e.g. an HTML page from a proxy), which mustn't hide
+		// the status error
+		if handlerErr != nil {
+			return nil, errors.Join(statusErr, handlerErr)
+		}
+
+		if httpResponse.StatusCode >= http.StatusBadRequest {
+			statusErr.ErrBody = opts.errBody
 		}
+
+		return nil, statusErr
+	}
+
+	if handlerErr != nil {
+		return nil, handlerErr
 	}
 
 	return httpResponse, nil
 }
 
+type attemptTimeoutKey struct{}
+
+type chainKey struct{}
+
 func (c *Client) do() HandleFunc {
-	return func(_ context.Context, req *http.Request, _ Interceptor) (*http.Response, error) {
+	return func(ctx context.Context, req *http.Request, _ Interceptor) (*http.Response, error) {
+		var cancel context.CancelFunc
+		if timeout, ok := ctx.Value(attemptTimeoutKey{}).(time.Duration); ok {
+			var attemptCtx context.Context
+			attemptCtx, cancel = context.WithTimeoutCause(req.Context(), timeout, ErrAttemptTimeout)
+			req = req.WithContext(attemptCtx)
+		}
+
+		// progress is tracked here so that it reflects what's actually sent, once the request has been signed
+		progress, tracked := ctx.Value(uploadProgressKey{}).(*progressReporter)
+		if tracked && req.Body != nil && req.Body != http.NoBody {
+			req = req.Clone(req.Context())
+			req.Body = progress.track(req.Body, req.ContentLength)
+		}
+
 		httpResponse, err := c.httpClient.Do(req)
 		if err != nil {
+			if cancel != nil {
+				cancel()
+			}
+
+			if errors.Is(context.Cause(req.Context()), ErrAttemptTimeout) {
+				return nil, fmt.Errorf("failed to send HTTP request: %w: %w", ErrAttemptTimeout, err)
+			}
+
 			return nil, fmt.Errorf("failed to send HTTP request: %w", err)
 		}
 
@@ -127,6 +292,11 @@ func (c *Client) do() HandleFunc {
 			httpResponse.Request = req
 		}
 
+		// the attempt's deadline keeps applying while the body is read
+		if cancel != nil {
+			httpResponse.Body = onClose(httpResponse.Body, cancel)
+		}
+
 		return httpResponse, nil
 	}
 }
@@ -0,0 +1,795 @@
+package httpr
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSignature is returned when a message signature (RFC 9421) is missing or can't be verified.
+var ErrInvalidSignature = errors.New("invalid message signature")
+
+// p256ScalarSize is the size of each of the two integers that make up an ECDSA P-256 signature.
+const p256ScalarSize = 32
+
+// MessageSignatureKey is a key used to create or verify HTTP message signatures (RFC 9421).
+type MessageSignatureKey struct {
+	id     string
+	alg    string
+	sign   func(data []byte) ([]byte, error)
+	verify func(data, signature []byte) bool
+}
+
+// Ed25519SigningKey creates a key that signs with ed25519. it can also verify signatures.
+func Ed25519SigningKey(id string, key ed25519.PrivateKey) *MessageSignatureKey {
+	k := Ed25519VerifyingKey(id, key.Public().(ed25519.PublicKey)) //nolint:forcetypeassert // always an ed25519.PublicKey
+	k.sign = func(data []byte) ([]byte, error) {
+		return ed25519.Sign(key, data), nil
+	}
+
+	return k
+}
+
+// Ed25519VerifyingKey creates a key that verifies ed25519 signatures.
+func Ed25519VerifyingKey(id string, key ed25519.PublicKey) *MessageSignatureKey {
+	return &MessageSignatureKey{
+		id:  id,
+		alg: "ed25519",
+		verify: func(data, signature []byte) bool {
+			return ed25519.Verify(key, data, signature)
+		},
+	}
+}
+
+// ECDSAP256SigningKey creates a key that signs with ECDSA using curve P-256 and SHA-256. it can also verify
+// signatures.
+func ECDSAP256SigningKey(id string, key *ecdsa.PrivateKey) *MessageSignatureKey {
+	k := ECDSAP256VerifyingKey(id, &key.PublicKey)
+	k.sign = func(data []byte) ([]byte, error) {
+		digest := sha256.Sum256(data)
+
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign: %w", err)
+		}
+
+		// the signature is the concatenation of r and s (RFC 9421 section 3.3.4)
+		signature := make([]byte, 2*p256ScalarSize)
+		r.FillBytes(signature[:p256ScalarSize])
+		s.FillBytes(signature[p256ScalarSize:])
+
+		return signature, nil
+	}
+
+	return k
+}
+
+// ECDSAP256VerifyingKey creates a key that verifies ECDSA signatures using curve P-256 and SHA-256.
+func ECDSAP256VerifyingKey(id string, key *ecdsa.PublicKey) *MessageSignatureKey {
+	return &MessageSignatureKey{
+		id:  id,
+		alg: "ecdsa-p256-sha256",
+		verify: func(data, signature []byte) bool {
+			if len(signature) != 2*p256ScalarSize {
+				return false
+			}
+
+			digest := sha256.Sum256(data)
+			r := new(big.Int).SetBytes(signature[:p256ScalarSize])
+			s := new(big.Int).SetBytes(signature[p256ScalarSize:])
+
+			return ecdsa.Verify(key, digest[:], r, s)
+		},
+	}
+}
+
+// HMACSHA256Key creates a key that signs and verifies with HMAC-SHA256 using a shared secret.
+func HMACSHA256Key(id string, secret []byte) *MessageSignatureKey {
+	mac := func(data []byte) []byte {
+		h := hmac.New(sha256.New, secret)
+		h.Write(data)
+
+		return h.Sum(nil)
+	}
+
+	return &MessageSignatureKey{
+		id:  id,
+		alg: "hmac-sha256",
+		sign: func(data []byte) ([]byte, error) {
+			return mac(data), nil
+		},
+		verify: func(data, signature []byte) bool {
+			return hmac.Equal(mac(data), signature)
+		},
+	}
+}
+
+// MessageSigner signs requests with HTTP message signatures (RFC 9421), adding Signature-Input, Signature and,
+// for messages with a body, Content-Digest (RFC 9530) headers. use it with [Sign].
+type MessageSigner struct {
+	key        *MessageSignatureKey
+	label      string
+	components []string
+	expiry     time.Duration
+	nonce      bool
+	tag        string
+}
+
+var _ Interceptor = (*MessageSigner)(nil)
+
+type MessageSignerOption func(*MessageSigner)
+
+// WithCoveredComponents sets the components covered by the signature, e.g. "@method", "@target-uri",
+// "content-digest" or any header name. signing fails if a covered header is missing. Defaults to "@method" and
+// "@target-uri" for requests, "@status" for responses, along with "content-digest" and "content-type" if present.
+func WithCoveredComponents(components ...string) MessageSignerOption {
+	return func(s *MessageSigner) {
+		s.components = components
+	}
+}
+
+// WithSignatureLabel sets the label that identifies the signature within the headers. Defaults to sig1.
+func WithSignatureLabel(label string) MessageSignerOption {
+	return func(s *MessageSigner) {
+		s.label = label
+	}
+}
+
+// WithSignatureExpiry adds an expires parameter to signatures so that they can't be used after d has passed.
+func WithSignatureExpiry(d time.Duration) MessageSignerOption {
+	return func(s *MessageSigner) {
+		s.expiry = d
+	}
+}
+
+// WithSignatureNonce adds a random nonce parameter to signatures, which allows servers to detect replays.
+func WithSignatureNonce() MessageSignerOption {
+	return func(s *MessageSigner) {
+		s.nonce = true
+	}
+}
+
+// WithSignatureTag adds a tag parameter to signatures, which identifies the application or profile they're for.
+func WithSignatureTag(tag string) MessageSignerOption {
+	return func(s *MessageSigner) {
+		s.tag = tag
+	}
+}
+
+// NewMessageSigner creates a signer that signs with key.
+func NewMessageSigner(key *MessageSignatureKey, opts ...MessageSignerOption) *MessageSigner {
+	s := &MessageSigner{
+		key:   key,
+		label: "sig1",
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func (s *MessageSigner) Handle(ctx context.Context, req *http.Request, next Interceptor) (*http.Response, error) {
+	if err := s.SignRequest(req); err != nil {
+		return nil, err
+	}
+
+	return next.Handle(ctx, req, nil)
+}
+
+// SignRequest signs req. the body is read into memory to compute its digest.
+func (s *MessageSigner) SignRequest(req *http.Request) error {
+	body, err := readBody(&req.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read request body for signing: %w", err)
+	}
+
+	if body != nil {
+		req.ContentLength = int64(len(body))
+	}
+
+	return s.sign(requestMessage(req), body, []string{"@method", "@target-uri"})
+}
+
+// SignResponse signs resp, e.g. in tests or proxies. the body is read into memory to compute its digest.
+func (s *MessageSigner) SignResponse(resp *http.Response) error {
+	body, err := readBody(&resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body for signing: %w", err)
+	}
+
+	return s.sign(responseMessage(resp), body, []string{"@status"})
+}
+
+func (s *MessageSigner) sign(msg signatureMessage, body []byte, defaults []string) error {
+	if s.key.sign == nil {
+		return fmt.Errorf("key %q can only verify signatures", s.key.id)
+	}
+
+	components := s.components
+	if body != nil || slices.Contains(components, "content-digest") {
+		sum := sha256.Sum256(body)
+		msg.header.Set("Content-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sum[:])+":")
+	}
+
+	if components == nil {
+		components = defaults
+		for _, name := range []string{"content-digest", "content-type"} {
+			if msg.header.Get(name) != "" {
+				components = append(components, name)
+			}
+		}
+	}
+
+	now := time.Now()
+	params := fmt.Sprintf("%s;created=%d;keyid=%s;alg=%s",
+		sfInnerList(components), now.Unix(), sfString(s.key.id), sfString(s.key.alg))
+
+	if s.expiry > 0 {
+		params += ";expires=" + strconv.FormatInt(now.Add(s.expiry).Unix(), 10)
+	}
+
+	if s.nonce {
+		nonce := make([]byte, 16)
+		if _, err := rand.Read(nonce); err != nil {
+			return fmt.Errorf("failed to generate nonce: %w", err)
+		}
+
+		params += ";nonce=" + sfString(base64.RawURLEncoding.EncodeToString(nonce))
+	}
+
+	if s.tag != "" {
+		params += ";tag=" + sfString(s.tag)
+	}
+
+	base, err := signatureBase(msg, components, params)
+	if err != nil {
+		return err
+	}
+
+	signature, err := s.key.sign([]byte(base))
+	if err != nil {
+		return err
+	}
+
+	msg.header.Set("Signature-Input", s.label+"="+params)
+	msg.header.Set("Signature", s.label+"=:"+base64.StdEncoding.EncodeToString(signature)+":")
+
+	return nil
+}
+
+// MessageVerifier is an interceptor that verifies HTTP message signatures (RFC 9421) of responses. responses
+// without a valid signature from one of its keys fail with [ErrInvalidSignature].
+type MessageVerifier struct {
+	keys     map[string]*MessageSignatureKey
+	required []string
+	maxAge   time.Duration
+}
+
+var _ Interceptor = (*MessageVerifier)(nil)
+
+type MessageVerifierOption func(*MessageVerifier)
+
+// WithRequiredComponents rejects signatures that don't cover all of the given components. regardless of this
+// option, signatures of messages with a body must cover content-digest.
+func WithRequiredComponents(components ...string) MessageVerifierOption {
+	return func(v *MessageVerifier) {
+		v.required = components
+	}
+}
+
+// WithMaxSignatureAge rejects signatures created longer than d ago.
+func WithMaxSignatureAge(d time.Duration) MessageVerifierOption {
+	return func(v *MessageVerifier) {
+		v.maxAge = d
+	}
+}
+
+// NewMessageVerifier creates a verifier that accepts signatures made with any of keys, matched by key ID.
+func NewMessageVerifier(keys []*MessageSignatureKey, opts ...MessageVerifierOption) *MessageVerifier {
+	v := &MessageVerifier{
+		keys: make(map[string]*MessageSignatureKey, len(keys)),
+	}
+
+	for _, key := range keys {
+		v.keys[key.id] = key
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v
+}
+
+func (v *MessageVerifier) Handle(ctx context.Context, req *http.Request, next Interceptor) (*http.Response, error) {
+	resp, err := next.Handle(ctx, req, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := v.VerifyResponse(resp); err != nil {
+		discardResponse(resp)
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// VerifyResponse verifies the signature of resp. the body is read into memory to check its digest.
+func (v *MessageVerifier) VerifyResponse(resp *http.Response) error {
+	body, err := readBody(&resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body for verification: %w", err)
+	}
+
+	return v.verify(responseMessage(resp), body, time.Now())
+}
+
+// VerifyRequest verifies the signature of req, e.g. in a server. the body is read into memory to check its digest.
+func (v *MessageVerifier) VerifyRequest(req *http.Request) error {
+	body, err := readBody(&req.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read request body for verification: %w", err)
+	}
+
+	return v.verify(requestMessage(req), body, time.Now())
+}
+
+func (v *MessageVerifier) verify(msg signatureMessage, body []byte, now time.Time) error {
+	inputs, err := parseSFDictionary(msg.header.Get("Signature-Input"))
+	if err != nil {
+		return fmt.Errorf("%w: malformed Signature-Input: %w", ErrInvalidSignature, err)
+	}
+
+	signatures, err := parseSFDictionary(msg.header.Get("Signature"))
+	if err != nil {
+		return fmt.Errorf("%w: malformed Signature: %w", ErrInvalidSignature, err)
+	}
+
+	for _, input := range inputs {
+		key, ok := v.keys[input.params["keyid"]]
+		if !ok {
+			continue
+		}
+
+		signature := signatures.get(input.key)
+		if signature == nil || signature.bytes == nil {
+			return fmt.Errorf("%w: no signature for %s", ErrInvalidSignature, input.key)
+		}
+
+		if err := v.check(msg, input, signature.bytes, key, body, now); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidSignature, input.key, err)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: no signature from a known key", ErrInvalidSignature)
+}
+
+func (v *MessageVerifier) check(
+	msg signatureMessage, input *sfMember, signature []byte, key *MessageSignatureKey, body []byte, now time.Time,
+) error {
+	if alg, ok := input.params["alg"]; ok && alg != key.alg {
+		return fmt.Errorf("algorithm %s doesn't match key %s", alg, key.id)
+	}
+
+	required := v.required
+	if len(body) > 0 {
+		required = append(slices.Clone(required), "content-digest")
+	}
+
+	for _, component := range required {
+		if !slices.Contains(input.items, component) {
+			return fmt.Errorf("%s is not covered", component)
+		}
+	}
+
+	if expires, ok := input.params["expires"]; ok {
+		if seconds, err := strconv.ParseInt(expires, 10, 64); err != nil || !now.Before(time.Unix(seconds, 0)) {
+			return errors.New("signature expired")
+		}
+	}
+
+	if v.maxAge > 0 {
+		seconds, err := strconv.ParseInt(input.params["created"], 10, 64)
+		if err != nil || now.Sub(time.Unix(seconds, 0)) > v.maxAge {
+			return errors.New("signature is too old")
+		}
+	}
+
+	base, err := signatureBase(msg, input.items, input.value)
+	if err != nil {
+		return err
+	}
+
+	if !key.verify([]byte(base), signature) {
+		return errors.New("signature mismatch")
+	}
+
+	if slices.Contains(input.items, "content-digest") {
+		return verifyContentDigest(msg.header.Get("Content-Digest"), body)
+	}
+
+	return nil
+}
+
+// verifyContentDigest checks body against a Content-Digest header (RFC 9530). at least one of the digests must use
+// a supported algorithm, and all supported digests must match.
+func verifyContentDigest(header string, body []byte) error {
+	digests, err := parseSFDictionary(header)
+	if err != nil {
+		return fmt.Errorf("malformed Content-Digest: %w", err)
+	}
+
+	verified := false
+	for _, digest := range digests {
+		var sum []byte
+		switch digest.key {
+		case "sha-256":
+			s := sha256.Sum256(body)
+			sum = s[:]
+		case "sha-512":
+			s := sha512.Sum512(body)
+			sum = s[:]
+		default:
+			continue
+		}
+
+		if !bytes.Equal(sum, digest.bytes) {
+			return fmt.Errorf("%s content digest mismatch", digest.key)
+		}
+
+		verified = true
+	}
+
+	if !verified {
+		return errors.New("no supported content digest")
+	}
+
+	return nil
+}
+
+// signatureMessage is a request or response whose components can be signed.
+type signatureMessage struct {
+	header http.Header
+	req    *http.Request
+	resp   *http.Response
+}
+
+func requestMessage(req *http.Request) signatureMessage {
+	return signatureMessage{header: req.Header, req: req}
+}
+
+func responseMessage(resp *http.Response) signatureMessage {
+	return signatureMessage{header: resp.Header, resp: resp}
+}
+
+// component returns the value of a component (RFC 9421 section 2).
+func (m signatureMessage) component(name string) (string, error) {
+	if !strings.HasPrefix(name, "@") {
+		values := m.header.Values(name)
+		if len(values) == 0 && name == "content-length" && m.req != nil && m.req.ContentLength > 0 {
+			return strconv.FormatInt(m.req.ContentLength, 10), nil
+		}
+
+		if len(values) == 0 {
+			return "", fmt.Errorf("component %s not found", name)
+		}
+
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.TrimSpace(value)
+		}
+
+		return strings.Join(trimmed, ", "), nil
+	}
+
+	if m.resp != nil {
+		if name == "@status" {
+			return strconv.Itoa(m.resp.StatusCode), nil
+		}
+
+		return "", fmt.Errorf("component %s is not supported for responses", name)
+	}
+
+	scheme := m.req.URL.Scheme
+	if scheme == "" {
+		scheme = "http"
+		if m.req.TLS != nil {
+			scheme = "https"
+		}
+	}
+
+	switch name {
+	case "@method":
+		return m.req.Method, nil
+	case "@target-uri":
+		return scheme + "://" + requestHost(m.req) + m.req.URL.RequestURI(), nil
+	case "@authority":
+		return strings.ToLower(requestHost(m.req)), nil
+	case "@scheme":
+		return strings.ToLower(scheme), nil
+	case "@request-target":
+		return m.req.URL.RequestURI(), nil
+	case "@path":
+		if path := m.req.URL.EscapedPath(); path != "" {
+			return path, nil
+		}
+
+		return "/", nil
+	case "@query":
+		return "?" + m.req.URL.RawQuery, nil
+	default:
+		return "", fmt.Errorf("component %s is not supported for requests", name)
+	}
+}
+
+// signatureBase builds the string that is signed (RFC 9421 section 2.5).
+func signatureBase(msg signatureMessage, components []string, params string) (string, error) {
+	var b strings.Builder
+	for _, component := range components {
+		value, err := msg.component(component)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(&b, "%s: %s\n", sfString(component), value)
+	}
+
+	fmt.Fprintf(&b, "%s: %s", sfString("@signature-params"), params)
+
+	return b.String(), nil
+}
+
+// readBody reads a body into memory and replaces it with a copy. the returned body is nil if there is none.
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+
+	*body = io.NopCloser(bytes.NewReader(data))
+
+	return data, nil
+}
+
+// sfString quotes s, escaping backslashes and quotes, which is how strings are written both in structured fields
+// and in authentication parameters.
+func sfString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func sfInnerList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = sfString(item)
+	}
+
+	return "(" + strings.Join(quoted, " ") + ")"
+}
+
+// sfMember is a member of a structured field dictionary (RFC 8941 section 3.2), limited to what's used by message
+// signatures and digests: inner lists of strings, byte sequences, and parameters.
+type sfMember struct {
+	key string
+	// value is the member's value as it appears in the field, including parameters.
+	value  string
+	items  []string
+	bytes  []byte
+	params map[string]string
+}
+
+type sfDictionary []*sfMember
+
+func (d sfDictionary) get(key string) *sfMember {
+	for _, member := range d {
+		if member.key == key {
+			return member
+		}
+	}
+
+	return nil
+}
+
+func parseSFDictionary(field string) (sfDictionary, error) {
+	p := &sfParser{s: field}
+
+	var dict sfDictionary
+	for {
+		p.skipSpaces()
+		if p.done() {
+			return dict, nil
+		}
+
+		member, err := p.member()
+		if err != nil {
+			return nil, err
+		}
+
+		dict = append(dict, member)
+
+		p.skipSpaces()
+		if p.done() {
+			return dict, nil
+		}
+
+		if !p.consume(',') {
+			return nil, fmt.Errorf("expected ',' at offset %d", p.pos)
+		}
+	}
+}
+
+type sfParser struct {
+	s   string
+	pos int
+}
+
+func (p *sfParser) done() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *sfParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+
+	return p.s[p.pos]
+}
+
+func (p *sfParser) consume(c byte) bool {
+	if p.peek() != c {
+		return false
+	}
+
+	p.pos++
+
+	return true
+}
+
+func (p *sfParser) skipSpaces() {
+	for p.peek() == ' ' || p.peek() == '\t' {
+		p.pos++
+	}
+}
+
+func (p *sfParser) member() (*sfMember, error) {
+	key := p.token()
+	if key == "" {
+		return nil, fmt.Errorf("expected key at offset %d", p.pos)
+	}
+
+	member := &sfMember{key: key, params: map[string]string{}}
+	if !p.consume('=') {
+		return member, nil
+	}
+
+	start := p.pos
+
+	switch p.peek() {
+	case '(':
+		p.pos++
+		for {
+			p.skipSpaces()
+			if p.consume(')') {
+				break
+			}
+
+			item, err := p.string()
+			if err != nil {
+				return nil, err
+			}
+
+			// parameters of components (e.g. ;sf or ;req) aren't supported
+			if p.peek() == ';' {
+				return nil, fmt.Errorf("unsupported component parameters at offset %d", p.pos)
+			}
+
+			member.items = append(member.items, item)
+		}
+	case ':':
+		end := strings.IndexByte(p.s[p.pos+1:], ':')
+		if end < 0 {
+			return nil, errors.New("unterminated byte sequence")
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(p.s[p.pos+1 : p.pos+1+end])
+		if err != nil {
+			return nil, fmt.Errorf("invalid byte sequence: %w", err)
+		}
+
+		member.bytes = decoded
+		p.pos += end + 2
+	default:
+		if p.token() == "" {
+			return nil, fmt.Errorf("unexpected character at offset %d", p.pos)
+		}
+	}
+
+	for p.consume(';') {
+		name := p.token()
+		if name == "" {
+			return nil, fmt.Errorf("expected parameter name at offset %d", p.pos)
+		}
+
+		value := ""
+		if p.consume('=') {
+			var err error
+			if p.peek() == '"' {
+				value, err = p.string()
+			} else {
+				value = p.token()
+			}
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		member.params[name] = value
+	}
+
+	member.value = p.s[start:p.pos]
+
+	return member, nil
+}
+
+// token reads a key, token or integer.
+func (p *sfParser) token() string {
+	start := p.pos
+	for !p.done() && strings.IndexByte(" \t,;=()\"", p.peek()) < 0 {
+		p.pos++
+	}
+
+	return p.s[start:p.pos]
+}
+
+func (p *sfParser) string() (string, error) {
+	if !p.consume('"') {
+		return "", fmt.Errorf("expected string at offset %d", p.pos)
+	}
+
+	var b strings.Builder
+	for !p.done() {
+		c := p.s[p.pos]
+		p.pos++
+
+		switch c {
+		case '\\':
+			if p.done() {
+				return "", errors.New("unterminated string")
+			}
+
+			b.WriteByte(p.s[p.pos])
+			p.pos++
+		case '"':
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", errors.New("unterminated string")
+}
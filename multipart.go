@@ -0,0 +1,183 @@
+package httpr
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MultipartPart is a part of a multipart/form-data request body. see [RequestBodyMultipart].
+type MultipartPart struct {
+	name        string
+	filename    string
+	contentType string
+	value       string
+	// open returns the content of file parts. nil for field parts.
+	open func() (io.ReadCloser, error)
+	// rewindable reports whether open can be called again. nil if it always can
+	rewindable func() bool
+}
+
+// MultipartField creates a form field part.
+func MultipartField(name, value string) MultipartPart {
+	return MultipartPart{name: name, value: value}
+}
+
+// MultipartFile creates a file part whose content is read from content. an empty content type defaults to
+// application/octet-stream. like [RequestBodyStream], content can only be sent again (e.g. retried) if it
+// implements io.Seeker.
+func MultipartFile(name, filename, contentType string, content io.Reader) MultipartPart {
+	stream := newRewindableStream(content)
+
+	return MultipartPart{
+		name:        name,
+		filename:    filename,
+		contentType: contentType,
+		open: func() (io.ReadCloser, error) {
+			r, err := stream.open()
+			if err != nil {
+				return nil, err
+			}
+
+			return io.NopCloser(r), nil
+		},
+		rewindable: stream.rewindable,
+	}
+}
+
+// MultipartFileFromPath creates a file part with the content of the file at path, named after the file. an empty
+// content type is guessed from the file's extension. the file is opened every time the request is sent.
+func MultipartFileFromPath(name, path, contentType string) MultipartPart {
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(path))
+	}
+
+	return MultipartPart{
+		name:        name,
+		filename:    filepath.Base(path),
+		contentType: contentType,
+		open: func() (io.ReadCloser, error) {
+			f, err := os.Open(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open multipart file: %w", err)
+			}
+
+			return f, nil
+		},
+	}
+}
+
+// RequestBodyMultipart sets the body to a multipart/form-data form made of parts, and the content type to
+// multipart/form-data with the form's boundary. the form is streamed as it's sent, so files are never buffered
+// in memory.
+func RequestBodyMultipart(parts ...MultipartPart) Option {
+	// the boundary has to stay the same when the body is recreated (e.g. retried) since the content type is only
+	// set once
+	boundary := multipart.NewWriter(nil).Boundary()
+
+	open := func() (io.Reader, string, error) {
+		// open every file before anything is sent so that e.g. missing files fail the request right away
+		contents := make([]io.ReadCloser, len(parts))
+		closeAll := func() {
+			for _, content := range contents {
+				if content != nil {
+					content.Close()
+				}
+			}
+		}
+
+		for i, part := range parts {
+			if part.open == nil {
+				continue
+			}
+
+			content, err := part.open()
+			if err != nil {
+				closeAll()
+				return nil, "", err
+			}
+
+			contents[i] = content
+		}
+
+		pr, pw := io.Pipe()
+		form := multipart.NewWriter(pw)
+		if err := form.SetBoundary(boundary); err != nil {
+			closeAll()
+			return nil, "", fmt.Errorf("failed to set multipart boundary: %w", err)
+		}
+
+		// closing the request body closes the pipe, which stops the writer if the form isn't read to the end
+		go func() {
+			defer closeAll()
+
+			err := writeMultipart(form, parts, contents)
+			if err == nil {
+				err = form.Close()
+			}
+
+			pw.CloseWithError(err)
+		}()
+
+		return pr, form.FormDataContentType(), nil
+	}
+
+	return requestBodyOption{handler: requestBodyHandler{open: open, rewindable: multipartRewindable(parts)}}
+}
+
+// multipartRewindable reports whether a form made of parts can be sent again.
+func multipartRewindable(parts []MultipartPart) func() bool {
+	return func() bool {
+		for _, part := range parts {
+			if part.rewindable != nil && !part.rewindable() {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+func writeMultipart(form *multipart.Writer, parts []MultipartPart, contents []io.ReadCloser) error {
+	for i, part := range parts {
+		if part.open == nil {
+			if err := form.WriteField(part.name, part.value); err != nil {
+				return fmt.Errorf("failed to write multipart field %s: %w", part.name, err)
+			}
+
+			continue
+		}
+
+		contentType := part.contentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			escapeQuotes(part.name), escapeQuotes(part.filename)))
+		header.Set("Content-Type", contentType)
+
+		w, err := form.CreatePart(header)
+		if err != nil {
+			return fmt.Errorf("failed to write multipart file %s: %w", part.name, err)
+		}
+
+		if _, err := io.Copy(w, contents[i]); err != nil {
+			return fmt.Errorf("failed to write multipart file %s: %w", part.name, err)
+		}
+	}
+
+	return nil
+}
+
+var quoteEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
@@ -0,0 +1,268 @@
+package httpr
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// oauth2Tokens caches client credentials access tokens across every [OAuth2TokenSource] in the process, so that
+// clients configured with the same credentials share tokens. refresh token sources act on behalf of a user, so they
+// each have a cache of their own.
+var oauth2Tokens = newOAuth2TokenCache()
+
+// OAuth2Error is returned when the token endpoint rejects a token request (RFC 6749 section 5.2).
+type OAuth2Error struct {
+	StatusCode  int    `json:"-"`
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+	URI         string `json:"error_uri"`
+}
+
+func (e *OAuth2Error) Error() string {
+	msg := fmt.Sprintf("oauth2: token request failed with status %d: %s", e.StatusCode, e.Code)
+	if e.Description != "" {
+		msg += ": " + e.Description
+	}
+
+	return msg
+}
+
+// OAuth2TokenSource is a [TokenSource] that obtains access tokens from an OAuth2 token endpoint. use it with
+// [BearerAuth].
+type OAuth2TokenSource struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	grantType    string
+	scopes       []string
+	audience     string
+	authInBody   bool
+	client       *Client
+	tokens       *oauth2TokenCache
+
+	mu           sync.Mutex
+	refreshToken string
+}
+
+var _ TokenSource = (*OAuth2TokenSource)(nil)
+
+type OAuth2Option func(*OAuth2TokenSource)
+
+// WithScopes sets the scopes to request.
+func WithScopes(scopes ...string) OAuth2Option {
+	return func(s *OAuth2TokenSource) {
+		s.scopes = scopes
+	}
+}
+
+// WithAudience sets the audience parameter used by some providers (e.g. Auth0) to select the API that the token is
+// for.
+func WithAudience(audience string) OAuth2Option {
+	return func(s *OAuth2TokenSource) {
+		s.audience = audience
+	}
+}
+
+// WithClientAuthInBody sends the client ID and secret as form parameters instead of using HTTP Basic authentication.
+func WithClientAuthInBody() OAuth2Option {
+	return func(s *OAuth2TokenSource) {
+		s.authInBody = true
+	}
+}
+
+// WithTokenClient sets the client used to talk to the token endpoint, e.g. to observe token requests. Defaults to
+// a client without any options.
+func WithTokenClient(client *Client) OAuth2Option {
+	return func(s *OAuth2TokenSource) {
+		s.client = client
+	}
+}
+
+// ClientCredentials creates a token source that uses the client credentials grant (RFC 6749 section 4.4).
+func ClientCredentials(tokenURL, clientID, clientSecret string, opts ...OAuth2Option) *OAuth2TokenSource {
+	return newOAuth2TokenSource(tokenURL, clientID, clientSecret, "client_credentials", opts)
+}
+
+// RefreshToken creates a token source that uses the refresh token grant (RFC 6749 section 6). if the token
+// endpoint rotates the refresh token, the new one is used from then on.
+func RefreshToken(tokenURL, clientID, clientSecret, refreshToken string, opts ...OAuth2Option) *OAuth2TokenSource {
+	s := newOAuth2TokenSource(tokenURL, clientID, clientSecret, "refresh_token", opts)
+	s.refreshToken = refreshToken
+	s.tokens = newOAuth2TokenCache()
+
+	return s
+}
+
+func newOAuth2TokenSource(tokenURL, clientID, clientSecret, grantType string, opts []OAuth2Option) *OAuth2TokenSource {
+	s := &OAuth2TokenSource{
+		tokenURL:     tokenURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		grantType:    grantType,
+		tokens:       oauth2Tokens,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.client == nil {
+		s.client = NewClient()
+	}
+
+	return s
+}
+
+// Token returns a cached access token for the source's token URL, credentials and scopes, or requests a new one.
+func (s *OAuth2TokenSource) Token(ctx context.Context) (string, time.Time, error) {
+	key := s.cacheKey()
+	if token, ok := s.tokens.get(key, time.Now()); ok {
+		return token.accessToken, token.expiry, nil
+	}
+
+	requestTime := time.Now()
+	resp, err := s.requestToken(ctx)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	var expiry time.Time
+	if resp.ExpiresIn > 0 {
+		expiry = requestTime.Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+
+	s.tokens.set(key, resp.AccessToken, expiry, requestTime)
+
+	return resp.AccessToken, expiry, nil
+}
+
+// invalidate evicts a token that was rejected by a server.
+func (s *OAuth2TokenSource) invalidate(token string) {
+	s.tokens.delete(s.cacheKey(), token)
+}
+
+type oauth2TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (s *OAuth2TokenSource) requestToken(ctx context.Context) (*oauth2TokenResponse, error) {
+	form := url.Values{"grant_type": {s.grantType}}
+	if len(s.scopes) > 0 {
+		form.Set("scope", strings.Join(s.scopes, " "))
+	}
+
+	if s.audience != "" {
+		form.Set("audience", s.audience)
+	}
+
+	// the refresh token is held until the response arrives so that a rotated token isn't used twice
+	if s.grantType == "refresh_token" {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		form.Set("refresh_token", s.refreshToken)
+	}
+
+	opts := []RequestOption{Header("Accept", "application/json")}
+	if s.authInBody {
+		form.Set("client_id", s.clientID)
+		form.Set("client_secret", s.clientSecret)
+	} else {
+		// RFC 6749 section 2.3.1 requires the credentials to be form-encoded before they're base64-encoded
+		opts = append(opts, BasicAuth(url.QueryEscape(s.clientID), url.QueryEscape(s.clientSecret)))
+	}
+
+	var token oauth2TokenResponse
+	var oauthErr OAuth2Error
+	opts = append(opts, RequestBodyForm(form), ResponseBodyJSON(&token, &oauthErr))
+
+	resp, err := s.client.Post(ctx, s.tokenURL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: failed to request token: %w", err)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		oauthErr.StatusCode = resp.StatusCode
+		return nil, &oauthErr
+	}
+
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("oauth2: token response from %s has no access token", s.tokenURL)
+	}
+
+	if token.RefreshToken != "" && s.grantType == "refresh_token" {
+		s.refreshToken = token.RefreshToken
+	}
+
+	return &token, nil
+}
+
+// cacheKey identifies the tokens of the source. the secret is hashed so that it isn't kept around in the cache.
+func (s *OAuth2TokenSource) cacheKey() string {
+	scopes := slices.Clone(s.scopes)
+	slices.Sort(scopes)
+
+	secret := sha256.Sum256([]byte(s.clientSecret))
+
+	return strings.Join([]string{
+		s.grantType, s.tokenURL, s.clientID, hex.EncodeToString(secret[:]), strings.Join(scopes, " "), s.audience,
+	}, "\n")
+}
+
+type oauth2TokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]oauth2CachedToken
+}
+
+func newOAuth2TokenCache() *oauth2TokenCache {
+	return &oauth2TokenCache{tokens: make(map[string]oauth2CachedToken)}
+}
+
+type oauth2CachedToken struct {
+	accessToken string
+	expiry      time.Time
+	refreshAt   time.Time
+}
+
+func (c *oauth2TokenCache) get(key string, now time.Time) (oauth2CachedToken, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	token, ok := c.tokens[key]
+	if !ok || (!token.refreshAt.IsZero() && !now.Before(token.refreshAt)) {
+		return oauth2CachedToken{}, false
+	}
+
+	return token, true
+}
+
+func (c *oauth2TokenCache) set(key, accessToken string, expiry time.Time, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.tokens[key] = oauth2CachedToken{
+		accessToken: accessToken,
+		expiry:      expiry,
+		refreshAt:   tokenRefreshAt(expiry, now),
+	}
+}
+
+// delete evicts the token cached under key, unless it has already been replaced by a different one.
+func (c *oauth2TokenCache) delete(key, accessToken string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.tokens[key].accessToken == accessToken {
+		delete(c.tokens, key)
+	}
+}
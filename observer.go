@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"go.opentelemetry.io/otel"
@@ -16,6 +17,14 @@ type Observer struct {
 	metricPrefix      string
 	requestCtr        metric.Int64Counter
 	roundtripDuration metric.Int64Histogram
+	retryBackoff      metric.Int64Histogram
+	retryBudget       metric.Float64Gauge
+	retryBudgetCtr    metric.Int64Counter
+	circuitState      metric.Int64Gauge
+	circuitCtr        metric.Int64Counter
+	rateLimitWait     metric.Int64Histogram
+	inflight          metric.Int64UpDownCounter
+	queued            metric.Int64UpDownCounter
 }
 
 var _ Interceptor = (*Observer)(nil)
@@ -56,8 +65,82 @@ func NewObserver(opts ...ObserverOption) (*Observer, error) {
 		return nil, fmt.Errorf("failed to create request duration histogram: %w", err)
 	}
 
+	retryBackoff, err := o.meter.Int64Histogram(
+		fmt.Sprintf("%s.retry.backoff", o.metricPrefix),
+		metric.WithDescription("Time spent waiting between retry attempts"),
+		metric.WithUnit("ms"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create retry backoff histogram: %w", err)
+	}
+
+	retryBudget, err := o.meter.Float64Gauge(
+		fmt.Sprintf("%s.retry.budget", o.metricPrefix),
+		metric.WithDescription("Retries left in the client's retry budget"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create retry budget gauge: %w", err)
+	}
+
+	retryBudgetCtr, err := o.meter.Int64Counter(
+		fmt.Sprintf("%s.retry.budget.exhausted", o.metricPrefix),
+		metric.WithDescription("Number of retries skipped because the client's retry budget was exhausted"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create retry budget counter: %w", err)
+	}
+
+	circuitState, err := o.meter.Int64Gauge(
+		fmt.Sprintf("%s.circuit.state", o.metricPrefix),
+		metric.WithDescription("State of a host's circuit (0 = closed, 1 = open, 2 = half-open)"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create circuit state gauge: %w", err)
+	}
+
+	circuitCtr, err := o.meter.Int64Counter(
+		fmt.Sprintf("%s.circuit.transitions", o.metricPrefix),
+		metric.WithDescription("Number of circuit breaker state transitions"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create circuit transition counter: %w", err)
+	}
+
+	rateLimitWait, err := o.meter.Int64Histogram(
+		fmt.Sprintf("%s.ratelimit.wait", o.metricPrefix),
+		metric.WithDescription("Time spent waiting to stay within rate limits"),
+		metric.WithUnit("ms"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rate limit wait histogram: %w", err)
+	}
+
+	inflight, err := o.meter.Int64UpDownCounter(
+		fmt.Sprintf("%s.concurrency.inflight", o.metricPrefix),
+		metric.WithDescription("Number of requests in flight through the concurrency limiter"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create in-flight counter: %w", err)
+	}
+
+	queued, err := o.meter.Int64UpDownCounter(
+		fmt.Sprintf("%s.concurrency.queued", o.metricPrefix),
+		metric.WithDescription("Number of requests waiting for a concurrency slot"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create queue length counter: %w", err)
+	}
+
 	o.requestCtr = requestCtr
 	o.roundtripDuration = roundtripDuration
+	o.retryBackoff = retryBackoff
+	o.retryBudget = retryBudget
+	o.retryBudgetCtr = retryBudgetCtr
+	o.circuitState = circuitState
+	o.circuitCtr = circuitCtr
+	o.rateLimitWait = rateLimitWait
+	o.inflight = inflight
+	o.queued = queued
 
 	return o, nil
 }
@@ -65,6 +148,10 @@ func NewObserver(opts ...ObserverOption) (*Observer, error) {
 func (o *Observer) Handle(ctx context.Context, req *http.Request, next Interceptor) (*http.Response, error) {
 	startTime := time.Now()
 
+	// allow interceptors further down the chain to contribute attributes and metrics
+	obs := &observation{observer: o, method: req.Method, host: req.URL.Host}
+	ctx = context.WithValue(ctx, observationKey{}, obs)
+
 	// Call next interceptor
 	resp, err := next.Handle(ctx, req, nil)
 
@@ -83,9 +170,149 @@ func (o *Observer) Handle(ctx context.Context, req *http.Request, next Intercept
 		attrs = append(attrs, attribute.Int("http.status_code", resp.StatusCode))
 	}
 
+	attrs = append(attrs, obs.attributes()...)
+
 	// Record metrics
 	o.requestCtr.Add(ctx, 1, metric.WithAttributes(attrs...))
 	o.roundtripDuration.Record(ctx, duration, metric.WithAttributes(attrs...))
 
 	return resp, err
 }
+
+type observationKey struct{}
+
+// observation is placed in the context by [Observer.Handle] so that interceptors further down the chain
+// can annotate the request's metrics and record metrics of their own. all methods are safe to call on a nil
+// observation, which is what interceptors get when no Observer precedes them in the chain.
+type observation struct {
+	observer *Observer
+	method   string
+	host     string
+
+	mu    sync.Mutex
+	attrs []attribute.KeyValue
+}
+
+func observationFromContext(ctx context.Context) *observation {
+	obs, _ := ctx.Value(observationKey{}).(*observation)
+	return obs
+}
+
+// annotate adds attributes to the metrics recorded for the request. an attribute that was previously added with
+// the same key is replaced.
+func (obs *observation) annotate(attrs ...attribute.KeyValue) {
+	if obs == nil {
+		return
+	}
+
+	obs.mu.Lock()
+	defer obs.mu.Unlock()
+
+	for _, attr := range attrs {
+		replaced := false
+		for i, existing := range obs.attrs {
+			if existing.Key == attr.Key {
+				obs.attrs[i] = attr
+				replaced = true
+				break
+			}
+		}
+
+		if !replaced {
+			obs.attrs = append(obs.attrs, attr)
+		}
+	}
+}
+
+func (obs *observation) attributes() []attribute.KeyValue {
+	obs.mu.Lock()
+	defer obs.mu.Unlock()
+
+	return append([]attribute.KeyValue(nil), obs.attrs...)
+}
+
+func (obs *observation) requestAttributes(attrs ...attribute.KeyValue) metric.MeasurementOption {
+	return metric.WithAttributes(append([]attribute.KeyValue{
+		attribute.String("http.method", obs.method),
+		attribute.String("http.host", obs.host),
+	}, attrs...)...)
+}
+
+// recordRetryBackoff records time spent waiting before a retry. serverDirected indicates that the wait was
+// requested by the server (e.g. Retry-After) rather than computed by the client.
+func (obs *observation) recordRetryBackoff(ctx context.Context, wait time.Duration, serverDirected bool) {
+	if obs == nil {
+		return
+	}
+
+	obs.observer.retryBackoff.Record(ctx, wait.Milliseconds(), obs.requestAttributes(
+		attribute.Bool("retry.server_directed", serverDirected),
+	))
+}
+
+// recordRetryBudget records the retries left in the client's retry budget and whether the retry was allowed.
+func (obs *observation) recordRetryBudget(ctx context.Context, remaining float64, allowed bool) {
+	if obs == nil || retryBudgetFromContext(ctx) == nil {
+		return
+	}
+
+	obs.observer.retryBudget.Record(ctx, remaining)
+	if !allowed {
+		obs.observer.retryBudgetCtr.Add(ctx, 1, obs.requestAttributes())
+	}
+}
+
+// recordCircuitTransition records a host's circuit moving from one state to another.
+func (obs *observation) recordCircuitTransition(ctx context.Context, host string, from, to CircuitState) {
+	if obs == nil {
+		return
+	}
+
+	obs.observer.circuitState.Record(ctx, int64(to), metric.WithAttributes(attribute.String("http.host", host)))
+	obs.observer.circuitCtr.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("http.host", host),
+		attribute.String("circuit.from", from.String()),
+		attribute.String("circuit.to", to.String()),
+	))
+}
+
+// recordRateLimitWait records time spent waiting before sending a request. adaptive indicates that the wait was
+// derived from the server's rate limit headers rather than a client-side limit.
+func (obs *observation) recordRateLimitWait(ctx context.Context, wait time.Duration, adaptive bool) {
+	if obs == nil {
+		return
+	}
+
+	obs.observer.rateLimitWait.Record(ctx, wait.Milliseconds(), obs.requestAttributes(
+		attribute.Bool("ratelimit.adaptive", adaptive),
+	))
+}
+
+// recordInflight records a change in the number of in-flight requests for host, or globally if host is empty.
+func (obs *observation) recordInflight(ctx context.Context, host string, delta int64) {
+	if obs == nil {
+		return
+	}
+
+	obs.observer.inflight.Add(ctx, delta, concurrencyAttributes(host))
+}
+
+// recordQueued records a change in the number of queued requests for host, or globally if host is empty.
+func (obs *observation) recordQueued(ctx context.Context, host string, delta int64) {
+	if obs == nil {
+		return
+	}
+
+	obs.observer.queued.Add(ctx, delta, concurrencyAttributes(host))
+}
+
+func concurrencyAttributes(host string) metric.MeasurementOption {
+	if host == "" {
+		return metric.WithAttributes(attribute.String("concurrency.scope", "global"))
+	}
+
+	return metric.WithAttributes(
+		attribute.String("concurrency.scope", "host"),
+		attribute.String("http.host", host),
+	)
+}
@@ -3,11 +3,13 @@ package httpr
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/alecthomas/types/optional"
@@ -27,11 +29,22 @@ type Option interface {
 }
 
 type requestOptions struct {
-	requestBody  optional.Option[requestBodyHandler]
-	responseBody optional.Option[responseBodyHandler]
-	queryParams  optional.Option[url.Values]
-	headers      map[string]string
-	interceptors []Interceptor
+	requestBody    optional.Option[requestBodyHandler]
+	responseBody   optional.Option[responseBodyHandler]
+	queryParams    optional.Option[url.Values]
+	headers        map[string]string
+	interceptors   []Interceptor
+	signers        []Interceptor
+	allowHedge     bool
+	timeout        time.Duration
+	attemptTimeout time.Duration
+
+	uploadProgress   *progressReporter
+	downloadProgress *progressReporter
+
+	// errBody is where the response body handler decodes error responses, if anywhere
+	errBody       any
+	errorOnStatus func(statusCode int) bool
 }
 
 type baseURLOption string
@@ -115,10 +128,48 @@ func (t timeoutOption) Client(c *Client) {
 	}
 }
 
+// Timeout sets the timeout of the underlying http.Client, which bounds every request it sends. see [RequestTimeout]
+// and [AttemptTimeout] for timeouts that take interceptors (e.g. retries) into account.
 func Timeout(timeout time.Duration) ClientOption {
 	return timeoutOption(timeout)
 }
 
+var (
+	// ErrRequestTimeout is the cause of errors returned when a request exceeds its [RequestTimeout].
+	ErrRequestTimeout = errors.New("request timeout exceeded")
+	// ErrAttemptTimeout is the cause of errors returned when a single attempt exceeds its [AttemptTimeout].
+	ErrAttemptTimeout = errors.New("attempt timeout exceeded")
+)
+
+type requestTimeoutOption time.Duration
+
+func (t requestTimeoutOption) Request(r *requestOptions) {
+	r.timeout = time.Duration(t)
+}
+
+// RequestTimeout bounds the entire request, including every interceptor (e.g. all retry attempts and the waits
+// between them) and reading the response body. errors caused by the timeout match [ErrRequestTimeout] and
+// context.DeadlineExceeded.
+func RequestTimeout(timeout time.Duration) RequestOption {
+	return requestTimeoutOption(timeout)
+}
+
+type attemptTimeoutOption time.Duration
+
+func (t attemptTimeoutOption) Client(c *Client) {
+	c.attemptTimeout = time.Duration(t)
+}
+
+func (t attemptTimeoutOption) Request(r *requestOptions) {
+	r.attemptTimeout = time.Duration(t)
+}
+
+// AttemptTimeout bounds every individual attempt at sending the request (e.g. each retry) separately from the
+// overall deadline. errors caused by the timeout match [ErrAttemptTimeout] and context.DeadlineExceeded.
+func AttemptTimeout(timeout time.Duration) Option {
+	return attemptTimeoutOption(timeout)
+}
+
 type interceptOption struct {
 	Interceptor
 }
@@ -135,7 +186,32 @@ func Intercept(i Interceptor) Option {
 	return interceptOption{i}
 }
 
-type requestBodyHandler func() (io.Reader, string, error)
+type signOption struct {
+	Interceptor
+}
+
+func (s signOption) Client(c *Client) {
+	c.signers = append(c.signers, s.Interceptor)
+}
+
+func (s signOption) Request(r *requestOptions) {
+	r.signers = append(r.signers, s.Interceptor)
+}
+
+// Sign adds an interceptor that signs requests, e.g. a [SigV4Signer]. signers run after every other interceptor,
+// right before the request is sent, so that they see the final request. every attempt (e.g. each retry) is signed
+// separately.
+func Sign(signer Interceptor) Option {
+	return signOption{signer}
+}
+
+// requestBodyHandler produces the body of a request along with its content type.
+type requestBodyHandler struct {
+	open func() (io.Reader, string, error)
+	// rewindable reports whether open can be called again to send the body once more (e.g. retries, redirects). nil
+	// if it always can
+	rewindable func() bool
+}
 
 type requestBodyOption struct {
 	handler requestBodyHandler
@@ -150,11 +226,13 @@ func (r requestBodyOption) Client(c *Client) {
 }
 
 func RequestBody(contentType string, bodyFunc func() (io.Reader, error)) Option {
-	return requestBodyOption{
-		handler: func() (io.Reader, string, error) {
-			body, err := bodyFunc()
-			return body, contentType, err
-		},
+	return requestBodyOption{handler: requestBodyHandler{open: withContentType(contentType, bodyFunc)}}
+}
+
+func withContentType(contentType string, bodyFunc func() (io.Reader, error)) func() (io.Reader, string, error) {
+	return func() (io.Reader, string, error) {
+		body, err := bodyFunc()
+		return body, contentType, err
 	}
 }
 
@@ -191,28 +269,101 @@ func RequestBodyBytes(contentType string, body []byte) Option {
 }
 
 // RequestBodyStream sets the content type to the provided value. good for when you have a stream of data.
+// if body can be seeked it is rewound whenever the request needs to be sent again (e.g. retries, redirects).
+// otherwise the body can only be sent once and attempts to send it again fail with [ErrBodyNotRewindable].
 func RequestBodyStream(contentType string, body io.Reader) Option {
-	return RequestBody(contentType, func() (io.Reader, error) {
-		return body, nil
-	})
+	stream := newRewindableStream(body)
+
+	return requestBodyOption{handler: requestBodyHandler{
+		open:       withContentType(contentType, stream.open),
+		rewindable: stream.rewindable,
+	}}
+}
+
+// rewindableStream hands out the content of a stream, rewinding it every time after the first if possible.
+type rewindableStream struct {
+	body io.Reader
+
+	mu          sync.Mutex
+	read        bool
+	seekable    bool
+	sectionable bool
+	start, end  int64
+}
+
+func newRewindableStream(body io.Reader) *rewindableStream {
+	return &rewindableStream{body: body}
+}
+
+// open returns the stream the first time it's called and a rewound stream every time after, if possible.
+func (s *rewindableStream) open() (io.Reader, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seeker, _ := s.body.(io.Seeker)
+	readerAt, _ := s.body.(io.ReaderAt)
+
+	switch {
+	case !s.read && seeker != nil:
+		// not every seeker can actually seek (e.g. an *os.File reading from a pipe), those are sent once
+		if start, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			s.seekable, s.start = true, start
+		}
+
+		if s.seekable && readerAt != nil {
+			end, err := seeker.Seek(0, io.SeekEnd)
+			if err != nil {
+				return nil, fmt.Errorf("failed to determine stream size: %w", err)
+			}
+
+			s.sectionable, s.end = true, end
+		}
+	case s.read && s.seekable && !s.sectionable:
+		if _, err := seeker.Seek(s.start, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to rewind stream: %w", err)
+		}
+	case s.read && !s.seekable:
+		return nil, ErrBodyNotRewindable
+	}
+
+	s.read = true
+
+	// readers that support random access are read through independent sections so that concurrent
+	// copies of the request (e.g. hedging) don't step on each other
+	if s.sectionable {
+		return io.NewSectionReader(readerAt, s.start, s.end-s.start), nil
+	}
+
+	return s.body, nil
+}
+
+// rewindable reports whether the stream can be opened again.
+func (s *rewindableStream) rewindable() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return !s.read || s.seekable
 }
 
 type responseBodyHandler func(resp *http.Response) error
 
 type responseHandlerOption struct {
 	handler responseBodyHandler
+	errBody any
 }
 
 func (r responseHandlerOption) Request(opts *requestOptions) {
 	opts.responseBody = optional.Some(r.handler)
+	opts.errBody = r.errBody
 }
 
 func (r responseHandlerOption) Client(c *Client) {
 	c.responseBodyHandler = optional.Some(r.handler)
+	c.errBody = r.errBody
 }
 
 func ResponseBodyJSON(successBody any, errBody any) Option {
-	return responseHandlerOption{handler: func(resp *http.Response) error {
+	return responseHandlerOption{errBody: errBody, handler: func(resp *http.Response) error {
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
 			return fmt.Errorf("failed to read response body: %w", err)
@@ -0,0 +1,118 @@
+package httpr
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// Progress is a report of how much of a request or response body has been transferred.
+type Progress struct {
+	// Transferred is the number of bytes transferred so far.
+	Transferred int64
+	// Total is the size of the body according to its Content-Length, or -1 if it's unknown.
+	Total int64
+	// Elapsed is the time since the transfer started.
+	Elapsed time.Duration
+	// BytesPerSecond is the average throughput since the transfer started.
+	BytesPerSecond float64
+	// Done is set on the last report, once the body has been transferred entirely.
+	Done bool
+}
+
+// ProgressFunc receives progress reports. it's called from the goroutine reading the body, so it should return
+// quickly.
+type ProgressFunc func(Progress)
+
+type uploadProgressKey struct{}
+
+type progressReporter struct {
+	report   ProgressFunc
+	interval time.Duration
+}
+
+type uploadProgressOption progressReporter
+
+func (u uploadProgressOption) Request(r *requestOptions) {
+	r.uploadProgress = &progressReporter{report: u.report, interval: u.interval}
+}
+
+// UploadProgress reports the progress of sending the request body to report, at most once every interval and
+// once more when it's been sent entirely. every attempt (e.g. retries) starts over from 0.
+func UploadProgress(report ProgressFunc, interval time.Duration) RequestOption {
+	return uploadProgressOption{report: report, interval: interval}
+}
+
+type downloadProgressOption progressReporter
+
+func (d downloadProgressOption) Request(r *requestOptions) {
+	r.downloadProgress = &progressReporter{report: d.report, interval: d.interval}
+}
+
+// DownloadProgress reports the progress of reading the response body to report, at most once every interval and
+// once more when it's been read entirely.
+func DownloadProgress(report ProgressFunc, interval time.Duration) RequestOption {
+	return downloadProgressOption{report: report, interval: interval}
+}
+
+// progressReader reports the progress of reading the body it wraps.
+type progressReader struct {
+	io.ReadCloser
+	reporter *progressReporter
+
+	mu          sync.Mutex
+	total       int64
+	transferred int64
+	start       time.Time
+	lastReport  time.Time
+	done        bool
+}
+
+// track wraps body to report the progress of reading it. size is the Content-Length of the body, negative or 0 if
+// it's unknown.
+func (p *progressReporter) track(body io.ReadCloser, size int64) io.ReadCloser {
+	if size <= 0 {
+		size = -1
+	}
+
+	now := time.Now()
+
+	return &progressReader{ReadCloser: body, reporter: p, total: size, start: now, lastReport: now}
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+
+	r.mu.Lock()
+	if r.done {
+		r.mu.Unlock()
+		return n, err
+	}
+
+	now := time.Now()
+	r.transferred += int64(n)
+	r.done = errors.Is(err, io.EOF)
+
+	if !r.done && (n == 0 || now.Sub(r.lastReport) < r.reporter.interval) {
+		r.mu.Unlock()
+		return n, err
+	}
+
+	r.lastReport = now
+	progress := Progress{
+		Transferred: r.transferred,
+		Total:       r.total,
+		Elapsed:     now.Sub(r.start),
+		Done:        r.done,
+	}
+	r.mu.Unlock()
+
+	if seconds := progress.Elapsed.Seconds(); seconds > 0 {
+		progress.BytesPerSecond = float64(progress.Transferred) / seconds
+	}
+
+	r.reporter.report(progress)
+
+	return n, err
+}
@@ -0,0 +1,143 @@
+package httpr
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimiter is an interceptor that limits how many requests are sent per second using a token bucket.
+// requests wait for a token to become available, for as long as the request's context allows.
+type RateLimiter struct {
+	perSecond float64
+	burst     int
+	key       func(req *http.Request) string
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+var _ Interceptor = (*RateLimiter)(nil)
+
+type RateLimitOption func(*RateLimiter)
+
+// PerHost gives every host (req.URL.Host) its own bucket.
+func PerHost() RateLimitOption {
+	return PerKey(func(req *http.Request) string {
+		return req.URL.Host
+	})
+}
+
+// PerKey gives every key returned by key its own bucket, e.g. one per API key or per endpoint.
+func PerKey(key func(req *http.Request) string) RateLimitOption {
+	return func(r *RateLimiter) {
+		r.key = key
+	}
+}
+
+// RateLimit creates an interceptor that allows perSecond requests per second on average with bursts of up to
+// burst requests. by default a single bucket is shared by all requests; use [PerHost] or [PerKey] to split it up.
+// use it with [Intercept].
+func RateLimit(perSecond float64, burst int, opts ...RateLimitOption) *RateLimiter {
+	r := &RateLimiter{
+		perSecond: perSecond,
+		burst:     max(burst, 1),
+		key: func(*http.Request) string {
+			return ""
+		},
+		buckets: make(map[string]*tokenBucket),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+func (r *RateLimiter) Handle(ctx context.Context, req *http.Request, next Interceptor) (*http.Response, error) {
+	if r.perSecond <= 0 {
+		return next.Handle(ctx, req, nil)
+	}
+
+	bucket := r.bucket(r.key(req))
+
+	wait := bucket.reserve(time.Now())
+	observationFromContext(ctx).recordRateLimitWait(ctx, wait, false)
+
+	if wait > 0 {
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			bucket.cancel()
+			return nil, fmt.Errorf("rate limit wait of %s exceeds context deadline: %w", wait, context.DeadlineExceeded)
+		}
+
+		if err := sleep(ctx, wait); err != nil {
+			bucket.cancel()
+			return nil, fmt.Errorf("rate limit wait interrupted: %w", err)
+		}
+	}
+
+	return next.Handle(ctx, req, nil)
+}
+
+func (r *RateLimiter) bucket(key string) *tokenBucket {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bucket, ok := r.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{
+			perSecond: r.perSecond,
+			capacity:  float64(r.burst),
+			tokens:    float64(r.burst),
+			updatedAt: time.Now(),
+		}
+		r.buckets[key] = bucket
+	}
+
+	return bucket
+}
+
+// tokenBucket hands out tokens at a steady rate, holding on to at most capacity of them. tokens can be borrowed
+// ahead of time, in which case the balance goes negative and the borrower has to wait for it to be paid back.
+type tokenBucket struct {
+	perSecond float64
+	capacity  float64
+
+	mu        sync.Mutex
+	tokens    float64
+	updatedAt time.Time
+}
+
+// reserve takes a token and returns how long to wait before using it.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(now)
+	b.tokens--
+
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.perSecond * float64(time.Second))
+}
+
+// cancel gives back a token that was reserved but won't be used.
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = min(b.tokens+1, b.capacity)
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updatedAt)
+	if elapsed > 0 {
+		b.tokens = min(b.tokens+elapsed.Seconds()*b.perSecond, b.capacity)
+		b.updatedAt = now
+	}
+}
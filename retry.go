@@ -0,0 +1,308 @@
+package httpr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxDiscardBytes is the most that will be read from a response body that is being thrown away.
+const maxDiscardBytes = 64 << 10
+
+// epochThreshold is used to tell apart reset headers that contain a unix timestamp from ones that contain
+// a number of seconds. no rate limit window is anywhere near 30 years long.
+const epochThreshold = 1_000_000_000
+
+// ErrBodyNotRewindable is returned when a request needs to be sent again (e.g. retried) but its body can only be read once.
+var ErrBodyNotRewindable = errors.New("request body cannot be rewound")
+
+// Retrier is an interceptor that retries requests that fail with a transport error or a retryable status code.
+// Backoff between attempts is exponential with full jitter, unless a 429 or 503 response tells the client how long
+// to wait via the Retry-After, RateLimit-Reset or X-RateLimit-Reset headers.
+type Retrier struct {
+	maxAttempts      int
+	statusCodes      map[int]bool
+	initialBackoff   time.Duration
+	maxBackoff       time.Duration
+	maxElapsedTime   time.Duration
+	maxServerBackoff time.Duration
+}
+
+var _ Interceptor = (*Retrier)(nil)
+
+type RetryOption func(*Retrier)
+
+// WithMaxAttempts sets the maximum number of attempts, including the first one. Defaults to 3.
+func WithMaxAttempts(attempts int) RetryOption {
+	return func(r *Retrier) {
+		r.maxAttempts = attempts
+	}
+}
+
+// WithRetryStatusCodes sets the response status codes that should be retried.
+// Defaults to 429, 502, 503 and 504.
+func WithRetryStatusCodes(codes ...int) RetryOption {
+	return func(r *Retrier) {
+		r.statusCodes = make(map[int]bool, len(codes))
+		for _, code := range codes {
+			r.statusCodes[code] = true
+		}
+	}
+}
+
+// WithBackoff sets the initial and maximum backoff between attempts. Defaults to 100ms and 10s.
+func WithBackoff(initial, maxBackoff time.Duration) RetryOption {
+	return func(r *Retrier) {
+		r.initialBackoff = initial
+		r.maxBackoff = maxBackoff
+	}
+}
+
+// WithMaxElapsedTime bounds the total time spent across all attempts. no further attempts are made once the
+// next one would start after this bound or after the request context's deadline. Defaults to no bound other than
+// the request context.
+func WithMaxElapsedTime(d time.Duration) RetryOption {
+	return func(r *Retrier) {
+		r.maxElapsedTime = d
+	}
+}
+
+// WithMaxRetryAfter caps how long the client is willing to wait when the server dictates the backoff
+// (e.g. via Retry-After). Defaults to 1 minute.
+func WithMaxRetryAfter(d time.Duration) RetryOption {
+	return func(r *Retrier) {
+		r.maxServerBackoff = d
+	}
+}
+
+// Retry creates an interceptor that retries failed requests. use it with [Intercept]. retries are subject to the
+// client's [RetryBudget], if one is set.
+func Retry(opts ...RetryOption) *Retrier {
+	r := &Retrier{
+		maxAttempts:      3,
+		initialBackoff:   100 * time.Millisecond,
+		maxBackoff:       10 * time.Second,
+		maxServerBackoff: time.Minute,
+	}
+
+	WithRetryStatusCodes(
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	)(r)
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+func (r *Retrier) Handle(ctx context.Context, req *http.Request, next Interceptor) (*http.Response, error) {
+	deadline := time.Time{}
+	if r.maxElapsedTime > 0 {
+		deadline = time.Now().Add(r.maxElapsedTime)
+	}
+
+	if ctxDeadline, ok := ctx.Deadline(); ok && (deadline.IsZero() || ctxDeadline.Before(deadline)) {
+		deadline = ctxDeadline
+	}
+
+	obs := observationFromContext(ctx)
+
+	attemptReq := req
+	for attempt := 1; ; attempt++ {
+		obs.annotate(attribute.Int("retry.attempts", attempt))
+
+		resp, err := next.Handle(ctx, attemptReq, nil)
+		if attempt >= r.maxAttempts || !r.shouldRetry(ctx, resp, err) {
+			return resp, err
+		}
+
+		wait, serverDirected := r.serverBackoff(resp)
+		if !serverDirected {
+			wait = r.backoff(attempt)
+		}
+
+		if !deadline.IsZero() && time.Until(deadline) < wait {
+			return resp, err
+		}
+
+		// rewind before discarding the response so that the caller still gets it if the body can't be sent again
+		nextReq, rewindErr := rewindRequest(req)
+		if rewindErr != nil {
+			return resp, err
+		}
+
+		allowed, remaining := retryBudgetFromContext(ctx).withdraw()
+		obs.recordRetryBudget(ctx, remaining, allowed)
+		if !allowed {
+			closeRequestBody(nextReq)
+			return resp, err
+		}
+
+		discardResponse(resp)
+
+		if serverDirected {
+			obs.annotate(attribute.Bool("retry.server_directed", true))
+		}
+		obs.recordRetryBackoff(ctx, wait, serverDirected)
+
+		if err := sleep(ctx, wait); err != nil {
+			closeRequestBody(nextReq)
+			return nil, fmt.Errorf("retry interrupted after %d attempts: %w", attempt, err)
+		}
+
+		attemptReq = nextReq
+	}
+}
+
+func (r *Retrier) shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if err != nil {
+		return true
+	}
+
+	return r.statusCodes[resp.StatusCode]
+}
+
+// backoff returns a random duration between 0 and the exponential backoff for the given attempt (full jitter).
+func (r *Retrier) backoff(attempt int) time.Duration {
+	if r.initialBackoff <= 0 {
+		return 0
+	}
+
+	ceiling := r.initialBackoff
+	for i := 1; i < attempt && ceiling < r.maxBackoff; i++ {
+		ceiling *= 2
+	}
+
+	if r.maxBackoff > 0 && ceiling > r.maxBackoff {
+		ceiling = r.maxBackoff
+	}
+
+	return rand.N(ceiling + 1) //nolint:gosec // jitter doesn't need a cryptographically secure source
+}
+
+// serverBackoff returns how long a 429 or 503 response asked the client to wait before trying again, capped at
+// the configured maximum. the second return value is false when the response doesn't say.
+func (r *Retrier) serverBackoff(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+
+	wait, ok := parseRetryAfter(resp.Header, time.Now())
+	if !ok {
+		return 0, false
+	}
+
+	if r.maxServerBackoff > 0 && wait > r.maxServerBackoff {
+		wait = r.maxServerBackoff
+	}
+
+	return wait, true
+}
+
+// parseRetryAfter reads the time to wait from Retry-After (delay in seconds or an HTTP-date), RateLimit-Reset
+// or X-RateLimit-Reset (delay in seconds or a unix timestamp), in that order of preference.
+func parseRetryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	if value := strings.TrimSpace(header.Get("Retry-After")); value != "" {
+		if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return max(time.Duration(seconds)*time.Second, 0), true
+		}
+
+		if date, err := http.ParseTime(value); err == nil {
+			return max(date.Sub(now), 0), true
+		}
+	}
+
+	for _, key := range []string{"Ratelimit-Reset", "X-Ratelimit-Reset"} {
+		if wait, ok := parseReset(header.Get(key), now); ok {
+			return wait, true
+		}
+	}
+
+	return 0, false
+}
+
+// parseReset reads the value of a rate limit reset header, which is either a number of seconds or a unix timestamp.
+func parseReset(value string, now time.Time) (time.Duration, bool) {
+	seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	if seconds >= epochThreshold {
+		return max(time.Unix(seconds, 0).Sub(now), 0), true
+	}
+
+	return max(time.Duration(seconds)*time.Second, 0), true
+}
+
+// rewindRequest returns a copy of req with a fresh body so that it can be sent again.
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return clone, nil
+	}
+
+	if req.GetBody == nil {
+		return nil, ErrBodyNotRewindable
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewind request body: %w", err)
+	}
+
+	clone.Body = body
+
+	return clone, nil
+}
+
+// closeRequestBody closes the body of a request that won't be sent, e.g. to stop the goroutine writing a streamed
+// body.
+func closeRequestBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// discardResponse drains and closes the response body so that the underlying connection can be reused.
+func discardResponse(resp *http.Response) {
+	if resp == nil || resp.Body == nil {
+		return
+	}
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDiscardBytes))
+	resp.Body.Close()
+}
+
+// sleep waits for d to elapse or for ctx to be done, whichever happens first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-timer.C:
+		return nil
+	}
+}
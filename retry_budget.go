@@ -0,0 +1,121 @@
+package httpr
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// retryBudgetWindow is how far back a retry budget looks when deciding whether a retry is allowed.
+const retryBudgetWindow = 10 * time.Second
+
+type retryBudgetKey struct{}
+
+// retryBudget caps the number of retries made by a [Client] to a fraction of the requests it recently sent, plus a
+// minimum number of retries per second so that clients sending few requests are still able to retry.
+type retryBudget struct {
+	ratio        float64
+	minPerSecond float64
+
+	mu       sync.Mutex
+	requests slidingCounter
+	retries  slidingCounter
+}
+
+type retryBudgetOption struct {
+	ratio        float64
+	minPerSecond float64
+}
+
+func (r retryBudgetOption) Client(c *Client) {
+	c.retryBudget = &retryBudget{
+		ratio:        r.ratio,
+		minPerSecond: r.minPerSecond,
+		requests:     newSlidingCounter(retryBudgetWindow),
+		retries:      newSlidingCounter(retryBudgetWindow),
+	}
+}
+
+// RetryBudget limits the retries made by [Retry] interceptors across all requests sent by a client. Over the last
+// 10 seconds, retries may make up at most ratio of the requests sent (e.g. 0.1 for 10%), plus minPerSecond retries
+// per second. once the budget is exhausted, retries are skipped and the original response or error is returned.
+func RetryBudget(ratio float64, minPerSecond float64) ClientOption {
+	return retryBudgetOption{ratio: ratio, minPerSecond: minPerSecond}
+}
+
+func retryBudgetFromContext(ctx context.Context) *retryBudget {
+	budget, _ := ctx.Value(retryBudgetKey{}).(*retryBudget)
+	return budget
+}
+
+// deposit records a request sent by the client.
+func (b *retryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.requests.add(time.Now())
+}
+
+// withdraw reports whether a retry is allowed and, if so, records it. the second return value is the number of
+// retries left in the budget afterwards. a nil budget allows every retry.
+func (b *retryBudget) withdraw() (bool, float64) {
+	if b == nil {
+		return true, 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	remaining := b.remaining(now)
+	if remaining < 1 {
+		return false, remaining
+	}
+
+	b.retries.add(now)
+
+	return true, remaining - 1
+}
+
+func (b *retryBudget) remaining(now time.Time) float64 {
+	allowed := b.minPerSecond*retryBudgetWindow.Seconds() + b.ratio*float64(b.requests.sum(now))
+	return allowed - float64(b.retries.sum(now))
+}
+
+// slidingCounter counts events over a sliding window using one bucket per second.
+type slidingCounter struct {
+	buckets []int64
+	epochs  []int64
+}
+
+func newSlidingCounter(window time.Duration) slidingCounter {
+	size := max(int(window/time.Second), 1)
+	return slidingCounter{
+		buckets: make([]int64, size),
+		epochs:  make([]int64, size),
+	}
+}
+
+func (s *slidingCounter) add(now time.Time) {
+	epoch := now.Unix()
+	i := int(epoch % int64(len(s.buckets)))
+	if s.epochs[i] != epoch {
+		s.epochs[i] = epoch
+		s.buckets[i] = 0
+	}
+
+	s.buckets[i]++
+}
+
+func (s *slidingCounter) sum(now time.Time) int64 {
+	oldest := now.Unix() - int64(len(s.buckets)) + 1
+
+	var total int64
+	for i, count := range s.buckets {
+		if s.epochs[i] >= oldest {
+			total += count
+		}
+	}
+
+	return total
+}
@@ -0,0 +1,334 @@
+package httpr
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat = "20060102T150405Z"
+	sigV4DateFormat = "20060102"
+
+	// UnsignedPayload is used in place of the payload hash when the request body isn't signed.
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// emptyPayloadHash is the hex encoded SHA-256 hash of an empty payload.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// sigV4IgnoredHeaders aren't signed because they may be changed or added after signing. Host is signed separately.
+var sigV4IgnoredHeaders = map[string]bool{
+	"Host":              true,
+	"Authorization":     true,
+	"User-Agent":        true,
+	"X-Amzn-Trace-Id":   true,
+	"Expect":            true,
+	"Transfer-Encoding": true,
+}
+
+// AWSCredentials are the credentials used to sign requests with [SigV4Signer].
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is set when using temporary credentials (e.g. from STS).
+	SessionToken string
+}
+
+// SigV4Signer signs requests with AWS Signature Version 4. use it with [Sign].
+type SigV4Signer struct {
+	credentials     AWSCredentials
+	region          string
+	service         string
+	unsignedPayload bool
+}
+
+var _ Interceptor = (*SigV4Signer)(nil)
+
+type SigV4Option func(*SigV4Signer)
+
+// WithUnsignedPayload leaves request bodies out of the signature so that they don't have to be read into memory to
+// be hashed, e.g. for large uploads to S3. bodies of unknown length (e.g. streams) are never signed.
+func WithUnsignedPayload() SigV4Option {
+	return func(s *SigV4Signer) {
+		s.unsignedPayload = true
+	}
+}
+
+// NewSigV4Signer creates a signer for the given region (e.g. us-east-1) and service (e.g. s3).
+func NewSigV4Signer(credentials AWSCredentials, region, service string, opts ...SigV4Option) *SigV4Signer {
+	s := &SigV4Signer{
+		credentials: credentials,
+		region:      region,
+		service:     service,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func (s *SigV4Signer) Handle(ctx context.Context, req *http.Request, next Interceptor) (*http.Response, error) {
+	if err := s.Sign(req, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return next.Handle(ctx, req, nil)
+}
+
+// Sign adds the Authorization, X-Amz-Date and, if needed, X-Amz-Security-Token and X-Amz-Content-Sha256 headers to
+// req. unless the payload is unsigned, the body is read into memory to be hashed.
+func (s *SigV4Signer) Sign(req *http.Request, signingTime time.Time) error {
+	signingTime = signingTime.UTC()
+
+	payloadHash, err := s.payloadHash(req)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Del("Authorization")
+	req.Header.Set("X-Amz-Date", signingTime.Format(sigV4TimeFormat))
+
+	if s.credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.credentials.SessionToken)
+	}
+
+	// S3 requires the payload hash to be sent along, other services only need it if it's not the actual hash
+	if s.service == "s3" || payloadHash == UnsignedPayload {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	headers := canonicalHeaders(req)
+	signature := s.signature(req, req.URL.Query(), headers, payloadHash, signingTime)
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, s.credential(signingTime), headers.names(), signature))
+
+	return nil
+}
+
+// Presign returns a URL for req that can be used without any credentials until it expires (e.g. to share an S3
+// object). only the Host header is signed and the payload is always unsigned.
+func (s *SigV4Signer) Presign(req *http.Request, expires time.Duration, signingTime time.Time) string {
+	signingTime = signingTime.UTC()
+
+	query := req.URL.Query()
+	query.Set("X-Amz-Algorithm", sigV4Algorithm)
+	query.Set("X-Amz-Credential", s.credential(signingTime))
+	query.Set("X-Amz-Date", signingTime.Format(sigV4TimeFormat))
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(expires/time.Second), 10))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	if s.credentials.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", s.credentials.SessionToken)
+	}
+
+	headers := sigV4Headers{{name: "host", value: requestHost(req)}}
+	query.Set("X-Amz-Signature", s.signature(req, query, headers, UnsignedPayload, signingTime))
+
+	presigned := *req.URL
+	presigned.RawQuery = canonicalQuery(query)
+
+	return presigned.String()
+}
+
+func (s *SigV4Signer) payloadHash(req *http.Request) (string, error) {
+	if hash := req.Header.Get("X-Amz-Content-Sha256"); hash != "" {
+		return hash, nil
+	}
+
+	if req.Body == nil || req.Body == http.NoBody {
+		return emptyPayloadHash, nil
+	}
+
+	// requests with bodies of unknown length have a content length of 0
+	if s.unsignedPayload || req.ContentLength <= 0 {
+		return UnsignedPayload, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to read request body for signing: %w", err)
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	sum := sha256.Sum256(body)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// signature computes the signature of a request as described in
+// https://docs.aws.amazon.com/IAM/latest/UserGuide/create-signed-request.html.
+func (s *SigV4Signer) signature(
+	req *http.Request, query url.Values, headers sigV4Headers, payloadHash string, signingTime time.Time,
+) string {
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s.canonicalURI(req.URL),
+		canonicalQuery(query),
+		headers.String(),
+		headers.names(),
+		payloadHash,
+	}, "\n")
+
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		signingTime.Format(sigV4TimeFormat),
+		s.scope(signingTime),
+		hex.EncodeToString(canonicalRequestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.credentials.SecretAccessKey), signingTime.Format(sigV4DateFormat))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, s.service)
+	key = hmacSHA256(key, "aws4_request")
+
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func (s *SigV4Signer) scope(signingTime time.Time) string {
+	return strings.Join([]string{signingTime.Format(sigV4DateFormat), s.region, s.service, "aws4_request"}, "/")
+}
+
+func (s *SigV4Signer) credential(signingTime time.Time) string {
+	return s.credentials.AccessKeyID + "/" + s.scope(signingTime)
+}
+
+// canonicalURI encodes the path of u. every service except S3 expects it to be encoded twice.
+func (s *SigV4Signer) canonicalURI(u *url.URL) string {
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	path = awsURIEncode(path, false)
+	if s.service != "s3" {
+		path = awsURIEncode(path, false)
+	}
+
+	return path
+}
+
+// canonicalQuery encodes query parameters sorted by name, then by value.
+func canonicalQuery(query url.Values) string {
+	params := make([][2]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			params = append(params, [2]string{awsURIEncode(key, true), awsURIEncode(value, true)})
+		}
+	}
+
+	slices.SortFunc(params, func(a, b [2]string) int {
+		return cmp.Or(strings.Compare(a[0], b[0]), strings.Compare(a[1], b[1]))
+	})
+
+	encoded := make([]string, len(params))
+	for i, param := range params {
+		encoded[i] = param[0] + "=" + param[1]
+	}
+
+	return strings.Join(encoded, "&")
+}
+
+type sigV4Header struct {
+	name  string
+	value string
+}
+
+// sigV4Headers are the signed headers of a request, sorted by name.
+type sigV4Headers []sigV4Header
+
+func canonicalHeaders(req *http.Request) sigV4Headers {
+	headers := sigV4Headers{{name: "host", value: requestHost(req)}}
+
+	for name, values := range req.Header {
+		if sigV4IgnoredHeaders[name] {
+			continue
+		}
+
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+
+		headers = append(headers, sigV4Header{name: strings.ToLower(name), value: strings.Join(trimmed, ",")})
+	}
+
+	slices.SortFunc(headers, func(a, b sigV4Header) int {
+		return strings.Compare(a.name, b.name)
+	})
+
+	return headers
+}
+
+func (h sigV4Headers) String() string {
+	var b strings.Builder
+	for _, header := range h {
+		b.WriteString(header.name)
+		b.WriteString(":")
+		b.WriteString(header.value)
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+func (h sigV4Headers) names() string {
+	names := make([]string, len(h))
+	for i, header := range h {
+		names[i] = header.name
+	}
+
+	return strings.Join(names, ";")
+}
+
+func requestHost(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+
+	return req.URL.Host
+}
+
+// awsURIEncode percent-encodes every byte of s except unreserved characters (RFC 3986 section 2.3) and, unless
+// encodeSlash is set, slashes.
+func awsURIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := range len(s) {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,86 @@
+package httpr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"unicode/utf8"
+)
+
+// maxStatusErrorBodySize is how much of the response body is kept in a [StatusError].
+const maxStatusErrorBodySize = 1024
+
+// StatusError is returned for responses whose status matches the predicate given to [ErrorOnStatus]. use errors.As
+// to get it.
+type StatusError struct {
+	StatusCode int
+	Header     http.Header
+	Method     string
+	URL        *url.URL
+	// Body is the beginning of the response body, up to 1 KiB.
+	Body []byte
+	// ErrBody is the error body decoded by [ResponseBodyJSON], if one was given and the status is >= 400. it's nil
+	// if the body couldn't be decoded, in which case the decoding error is joined to the StatusError.
+	ErrBody any
+}
+
+// Error doesn't include the query of the URL since it may contain credentials (e.g. [APIKeyInQuery]).
+func (e *StatusError) Error() string {
+	u := url.URL{Scheme: e.URL.Scheme, Host: e.URL.Host, Path: e.URL.Path}
+	msg := fmt.Sprintf("%s %s: unexpected status %d %s", e.Method, u.String(), e.StatusCode, http.StatusText(e.StatusCode))
+
+	if len(e.Body) > 0 && utf8.Valid(e.Body) {
+		msg += ": " + string(e.Body)
+	}
+
+	return msg
+}
+
+type errorOnStatusOption func(statusCode int) bool
+
+func (e errorOnStatusOption) Client(c *Client) {
+	c.errorOnStatus = e
+}
+
+func (e errorOnStatusOption) Request(r *requestOptions) {
+	r.errorOnStatus = e
+}
+
+// ErrorOnStatus makes requests fail with a *[StatusError] when the status of the response matches predicate, e.g.
+// for every status >= 400. the response body is still handled (e.g. by [ResponseBodyJSON]) before the error is
+// returned. by default, every status is returned as a response.
+func ErrorOnStatus(predicate func(statusCode int) bool) Option {
+	return errorOnStatusOption(predicate)
+}
+
+// statusError builds the error for the response to req, keeping the beginning of its body while leaving the body
+// intact for the response body handler.
+func statusError(req *http.Request, resp *http.Response) (*StatusError, error) {
+	statusErr := &StatusError{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Method:     req.Method,
+		URL:        req.URL,
+	}
+
+	if resp.Body == nil || resp.Body == http.NoBody {
+		return statusErr, nil
+	}
+
+	head := make([]byte, maxStatusErrorBodySize)
+	n, err := io.ReadFull(resp.Body, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	statusErr.Body = head[:n]
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(statusErr.Body), resp.Body), resp.Body}
+
+	return statusErr, nil
+}
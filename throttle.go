@@ -0,0 +1,185 @@
+package httpr
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Throttler is an interceptor that paces requests according to the rate limit headers returned by the server, so
+// that the remaining quota is spread evenly over what's left of the rate limit window instead of being used up
+// right away and running into 429s.
+type Throttler struct {
+	key     func(req *http.Request) string
+	maxWait time.Duration
+
+	mu     sync.Mutex
+	quotas map[string]*quota
+}
+
+var _ Interceptor = (*Throttler)(nil)
+
+// quota is what's known about the rate limit for a single key.
+type quota struct {
+	remaining int
+	resetAt   time.Time
+	next      time.Time
+}
+
+type ThrottleOption func(*Throttler)
+
+// WithThrottleKey sets how requests are grouped into separately tracked quotas. Defaults to one per host.
+func WithThrottleKey(key func(req *http.Request) string) ThrottleOption {
+	return func(t *Throttler) {
+		t.key = key
+	}
+}
+
+// WithMaxThrottleWait caps how long a request is held back. Defaults to no cap other than the request's context.
+func WithMaxThrottleWait(d time.Duration) ThrottleOption {
+	return func(t *Throttler) {
+		t.maxWait = d
+	}
+}
+
+// AdaptiveThrottle creates an interceptor that reads X-RateLimit-Remaining / X-RateLimit-Reset,
+// RateLimit-Remaining / RateLimit-Reset or RateLimit (IETF draft) response headers and delays subsequent requests
+// so that the remaining quota lasts until the window resets. use it with [Intercept].
+func AdaptiveThrottle(opts ...ThrottleOption) *Throttler {
+	t := &Throttler{
+		key: func(req *http.Request) string {
+			return req.URL.Host
+		},
+		quotas: make(map[string]*quota),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+func (t *Throttler) Handle(ctx context.Context, req *http.Request, next Interceptor) (*http.Response, error) {
+	key := t.key(req)
+
+	wait := t.reserve(key, time.Now())
+	if t.maxWait > 0 {
+		wait = min(wait, t.maxWait)
+	}
+
+	observationFromContext(ctx).recordRateLimitWait(ctx, wait, true)
+
+	if wait > 0 {
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return nil, fmt.Errorf("throttle wait of %s exceeds context deadline: %w", wait, context.DeadlineExceeded)
+		}
+
+		if err := sleep(ctx, wait); err != nil {
+			return nil, fmt.Errorf("throttle wait interrupted: %w", err)
+		}
+	}
+
+	resp, err := next.Handle(ctx, req, nil)
+	if err == nil {
+		t.update(key, resp.Header, time.Now())
+	}
+
+	return resp, err
+}
+
+// reserve returns how long to wait before sending a request for key and accounts for it in the known quota.
+func (t *Throttler) reserve(key string, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	q, ok := t.quotas[key]
+	if !ok {
+		return 0
+	}
+
+	if !now.Before(q.resetAt) {
+		delete(t.quotas, key)
+		return 0
+	}
+
+	start := now
+	if q.next.After(start) {
+		start = q.next
+	}
+
+	if q.remaining <= 0 {
+		start = q.resetAt
+	}
+
+	q.next = start.Add(q.resetAt.Sub(start) / time.Duration(max(q.remaining, 1)))
+	q.remaining--
+
+	return start.Sub(now)
+}
+
+func (t *Throttler) update(key string, header http.Header, now time.Time) {
+	remaining, reset, ok := parseRateLimit(header, now)
+	if !ok {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	q, exists := t.quotas[key]
+	if !exists {
+		q = &quota{}
+		t.quotas[key] = q
+	}
+
+	q.remaining = remaining
+	q.resetAt = now.Add(reset)
+}
+
+// parseRateLimit reads the remaining quota and the time until it resets from the response headers.
+func parseRateLimit(header http.Header, now time.Time) (int, time.Duration, bool) {
+	// RateLimit: limit=100, remaining=50, reset=30 (draft-ietf-httpapi-ratelimit-headers-07)
+	// RateLimit: "default";r=50;t=30 (draft-ietf-httpapi-ratelimit-headers-08 and later)
+	if value := header.Get("Ratelimit"); value != "" {
+		params := map[string]string{}
+		for _, part := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' }) {
+			if k, v, found := strings.Cut(strings.TrimSpace(part), "="); found {
+				params[strings.ToLower(k)] = strings.Trim(v, `"`)
+			}
+		}
+
+		remaining, remainingErr := strconv.Atoi(firstNonEmpty(params["r"], params["remaining"]))
+		reset, resetOK := parseReset(firstNonEmpty(params["t"], params["reset"]), now)
+		if remainingErr == nil && resetOK {
+			return remaining, reset, true
+		}
+	}
+
+	for _, prefix := range []string{"Ratelimit", "X-Ratelimit"} {
+		remaining, err := strconv.Atoi(strings.TrimSpace(header.Get(prefix + "-Remaining")))
+		if err != nil {
+			continue
+		}
+
+		if reset, ok := parseReset(header.Get(prefix+"-Reset"), now); ok {
+			return remaining, reset, true
+		}
+	}
+
+	return 0, 0, false
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
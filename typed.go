@@ -0,0 +1,108 @@
+package httpr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+)
+
+// HTTPError is returned by the typed request helpers (e.g. [GetJSON], [Do]) when the server responds with a
+// non-2xx status. use errors.As to get it.
+type HTTPError[E any] struct {
+	StatusCode int
+	Header     http.Header
+	// Body is the JSON decoded response body. it's the zero value if the body is empty or can't be decoded.
+	Body E
+	// RawBody is the response body as it was received.
+	RawBody []byte
+}
+
+func (e *HTTPError[E]) Error() string {
+	return fmt.Sprintf("unexpected status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// GetJSON sends a GET request and decodes the JSON response body into a T. non-2xx responses are returned as a
+// *[HTTPError] with the raw JSON body. see [DoWithError] to decode error bodies into a type of your own.
+func GetJSON[T any](ctx context.Context, c *Client, url string, options ...RequestOption) (T, *http.Response, error) {
+	return sendJSON[T, json.RawMessage](ctx, c, http.MethodGet, url, nil, options)
+}
+
+// Do sends a request with body encoded as JSON (if it isn't nil) and decodes the JSON response body into a Resp. non-2xx responses
+// are returned as a *[HTTPError] with the raw JSON body. see [DoWithError] to decode error bodies into a type of
+// your own.
+func Do[Req, Resp any](
+	ctx context.Context, c *Client, method, url string, body Req, options ...RequestOption,
+) (Resp, *http.Response, error) {
+	return sendJSON[Resp, json.RawMessage](ctx, c, method, url, jsonBody(body), options)
+}
+
+// DoWithError is like [Do], but decodes the body of non-2xx responses into the Body of an *[HTTPError] of E.
+func DoWithError[Req, Resp, E any](
+	ctx context.Context, c *Client, method, url string, body Req, options ...RequestOption,
+) (Resp, *http.Response, error) {
+	return sendJSON[Resp, E](ctx, c, method, url, jsonBody(body), options)
+}
+
+// jsonBody encodes body as JSON, unless it's a nil interface (e.g. Do[any, Resp] with a nil body), in which case
+// the request has no body.
+func jsonBody[Req any](body Req) RequestOption {
+	if any(body) == nil {
+		return nil
+	}
+
+	return RequestBodyJSON(body)
+}
+
+func sendJSON[Resp, E any](
+	ctx context.Context, c *Client, method, url string, body RequestOption, options []RequestOption,
+) (Resp, *http.Response, error) {
+	var result Resp
+	var httpErr *HTTPError[E]
+
+	decode := ResponseBody(func(resp *http.Response) error {
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+
+		if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+			httpErr = &HTTPError[E]{StatusCode: resp.StatusCode, Header: resp.Header, RawBody: data}
+			// the status is what matters, so an error body that can't be decoded is only available raw
+			_ = json.Unmarshal(data, &httpErr.Body)
+
+			return nil
+		}
+
+		if len(data) == 0 {
+			return nil
+		}
+
+		if err := json.Unmarshal(data, &result); err != nil {
+			return fmt.Errorf("failed to unmarshal %d response body: %w", resp.StatusCode, err)
+		}
+
+		return nil
+	})
+
+	// the body and decoder are applied last so that they aren't overridden by the options
+	options = slices.Concat(options, []RequestOption{decode})
+	if body != nil {
+		options = append(options, body)
+	}
+
+	resp, err := c.SendRequest(ctx, method, url, options...)
+	if err != nil {
+		return result, nil, err
+	}
+
+	if httpErr != nil {
+		return result, resp, httpErr
+	}
+
+	return result, resp, nil
+}